@@ -0,0 +1,223 @@
+package oram
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+)
+
+// InsertBatch 批量插入 []T 或 []*T，每条语句最多插入 batchSize 行，
+// batchSize <= 0 时一条语句插入尽可能多的数据，每条语句的参数个数不超过 Dialect 的 MaxBindVars。
+// 插入成功后生成的主键写回每个元素的 Id 字段
+func (m *ConDB) InsertBatch(list interface{}, batchSize int) error {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+
+	registerModel(list)
+
+	v := reflect.Indirect(reflect.ValueOf(list))
+	if v.Kind() != reflect.Slice {
+
+		db.trace("InsertBatch need a slice")
+		return errors.New("InsertBatch need a slice")
+	}
+
+	length := v.Len()
+	if length == 0 {
+		return nil
+	}
+	items := make([]reflect.Value, length)
+	for i := 0; i < length; i++ {
+
+		item := v.Index(i)
+		if item.Kind() != reflect.Ptr {
+			item = item.Addr()
+		}
+		items[i] = item
+	}
+
+	if db.table == "" {
+		db.table = getTable(items[0].Interface())
+	}
+
+	if batchSize <= 0 || batchSize > length {
+		batchSize = length
+	}
+	if cols := len(toMap(items[0], items[0].Type())); cols > 0 {
+
+		if max := db.getDialect().MaxBindVars() / cols; max > 0 && batchSize > max {
+			batchSize = max
+		}
+	}
+
+	for start := 0; start < length; start += batchSize {
+
+		end := start + batchSize
+		if end > length {
+			end = length
+		}
+		if err := db.insertBatch(items[start:end]); err != nil {
+			return err
+		}
+		for _, item := range items[start:end] {
+
+			if err := db.afterInsert(item.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (db *ConDB) insertBatch(items []reflect.Value) error {
+
+	d := db.getDialect()
+
+	rows := make([]map[string]interface{}, len(items))
+	auto := make([]bool, len(items))
+	count := 0
+
+	for i, item := range items {
+
+		legacyHook(item.Interface(), "PreInsert")
+		if err := db.beforeInsert(item.Interface()); err != nil {
+			return err
+		}
+
+		data := toMap(item, item.Type())
+		id, ok := data[getModel(item.Type()).keyColumn()]
+		auto[i] = !ok || isZero(bindValue(id))
+		if auto[i] {
+			count++
+		}
+		rows[i] = data
+	}
+
+	idKey := getModel(items[0].Type()).keyColumn()
+	generated := false
+	var seqs []int64
+
+	if seqSql := d.Sequences(db.table, count); count > 0 && seqSql != "" {
+
+		var err error
+		if seqs, err = db.sequences(seqSql, count); err != nil {
+			return err
+		}
+
+		n := 0
+		for i := range rows {
+
+			if auto[i] {
+				rows[i][idKey] = seqs[n]
+				n++
+			}
+		}
+	} else if count > 0 {
+
+		if count != len(rows) {
+
+			db.trace("InsertBatch can't mix rows with and without id")
+			return errors.New("InsertBatch can't mix rows with and without id")
+		}
+		for _, data := range rows {
+			delete(data, idKey)
+		}
+		generated = true
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for k := range rows[0] {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	args := make([]interface{}, 0, len(columns)*len(rows))
+	for _, data := range rows {
+
+		for _, col := range columns {
+			args = append(args, bindValue(data[col]))
+		}
+	}
+
+	sql := d.BatchInsert(db.table, columns, len(rows))
+
+	if generated && d.Returning(idKey) != "" {
+
+		sql += d.Returning(idKey)
+
+		ids, err := db.sequences(sql, len(rows), args...)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids {
+			setId(items[i], id)
+		}
+		return nil
+	}
+
+	db.Result, db.Err = db.execContext(sql, args...)
+	if db.Err != nil {
+
+		return db.Err
+	}
+
+	if seqs != nil {
+
+		n := 0
+		for i := range items {
+
+			if auto[i] {
+				setId(items[i], seqs[n])
+				n++
+			}
+		}
+	}
+
+	if generated {
+
+		last, err := db.Result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		first := d.FirstInsertId(last, len(rows))
+		for i := range items {
+			setId(items[i], first+int64(i))
+		}
+	}
+
+	return nil
+}
+
+// sequences 执行返回一列整数的查询，读取 n 个主键值
+func (db *ConDB) sequences(query string, n int, args ...interface{}) ([]int64, error) {
+
+	rows, err := db.queryContext(query, args...)
+	if err != nil {
+
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, n)
+	for rows.Next() {
+
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) != n {
+		return nil, errors.New("sequence values not match rows")
+	}
+	return ids, nil
+}
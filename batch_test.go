@@ -0,0 +1,112 @@
+package oram
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type batchPerson struct {
+	Id    int64  `db:"id"`
+	Name  string `db:"name"`
+	Phone string `db:"phone"`
+	City  string `db:"city"`
+	Age   int    `db:"age"`
+}
+
+func TestInsertBatchBindLimit(t *testing.T) {
+
+	tests := []struct {
+		dialect Dialect
+		rows    int
+		batch   int
+		want    []int // 每条语句插入的行数
+	}{
+		{SQLite, 500, 0, []int{199, 199, 102}},
+		{SQLite, 500, 1000, []int{199, 199, 102}},
+		{SQLite, 500, 300, []int{199, 199, 102}},
+		{SQLite, 500, 150, []int{150, 150, 150, 50}},
+		{MySQL, 20000, 0, []int{13107, 6893}},
+		{Oracle, 3, 2, []int{2, 1}},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(tt.dialect)
+		if tt.dialect == Oracle {
+			d.results = []*fakeRows{
+				{cols: []string{"n"}, data: [][]driver.Value{{int64(1)}, {int64(2)}}},
+				{cols: []string{"n"}, data: [][]driver.Value{{int64(3)}}},
+			}
+		}
+
+		list := make([]batchPerson, tt.rows)
+		if err := db.Table("tb_person").InsertBatch(list, tt.batch); err != nil {
+			t.Fatalf("%s: %v", tt.dialect.Name(), err)
+		}
+
+		var got []int
+		for _, s := range d.statements() {
+
+			if !strings.HasPrefix(s, "INSERT") {
+				continue
+			}
+			rows := strings.Count(s, "INTO tb_person")
+			if tt.dialect != Oracle {
+				rows = strings.Count(s, "),(") + 1
+			}
+			got = append(got, rows)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %d rows, batchSize %d: statements insert %v rows, want %v", tt.dialect.Name(), tt.rows, tt.batch, got, tt.want)
+		}
+	}
+}
+
+func TestInsertBatchIds(t *testing.T) {
+
+	ids := func(list []batchPerson) []int64 {
+		out := make([]int64, len(list))
+		for i, p := range list {
+			out[i] = p.Id
+		}
+		return out
+	}
+	seqs := func(v ...int64) *fakeRows {
+		r := &fakeRows{cols: []string{"n"}}
+		for _, id := range v {
+			r.data = append(r.data, []driver.Value{id})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		results []*fakeRows
+		failOn  string
+		list    []batchPerson
+		want    []int64
+		err     bool
+	}{
+		{"oracle sequences", Oracle, []*fakeRows{seqs(5, 6)}, "", []batchPerson{{}, {Id: 9}, {}}, []int64{5, 9, 6}, false},
+		{"oracle insert fails", Oracle, []*fakeRows{seqs(5, 6, 7)}, "INSERT ALL", make([]batchPerson, 3), []int64{0, 0, 0}, true},
+		{"mysql last insert id", MySQL, nil, "", make([]batchPerson, 3), []int64{7, 8, 9}, false},
+		{"sqlite last insert id", SQLite, nil, "", make([]batchPerson, 3), []int64{5, 6, 7}, false},
+		{"postgres returning", PostgreSQL, []*fakeRows{seqs(11, 12, 13)}, "", make([]batchPerson, 3), []int64{11, 12, 13}, false},
+		{"mixed ids", MySQL, nil, "", []batchPerson{{}, {Id: 9}}, []int64{0, 9}, true},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(tt.dialect)
+		d.results, d.failOn = tt.results, tt.failOn
+
+		err := db.Table("tb_person").InsertBatch(tt.list, 0)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.err)
+		}
+		if got := ids(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ids = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package oram
+
+import (
+	"reflect"
+	"strings"
+)
+
+// maxInItems Oracle IN 列表最多 1000 项，超过时拆分为多个 IN
+const maxInItems = 1000
+
+// Cond 可以组合的查询条件，Build 返回以 ? 为占位符的 SQL 片段和对应的参数，
+// 占位符在生成语句时按数据库方言统一编号
+type Cond interface {
+	Build() (string, []interface{})
+}
+
+type expr struct {
+	query string
+	args  []interface{}
+}
+
+func (e expr) Build() (string, []interface{}) {
+
+	return e.query, e.args
+}
+
+// Expr 以 SQL 片段作为条件，用法与 Where 相同，如 Expr("age>? AND age<?", 18, 60)
+func Expr(query string, args ...interface{}) Cond {
+
+	return expr{query, args}
+}
+
+// Eq 生成 column=?
+func Eq(column string, value interface{}) Cond {
+
+	return expr{column + "=?", []interface{}{value}}
+}
+
+// Ne 生成 column<>?
+func Ne(column string, value interface{}) Cond {
+
+	return expr{column + "<>?", []interface{}{value}}
+}
+
+// Gt 生成 column>?
+func Gt(column string, value interface{}) Cond {
+
+	return expr{column + ">?", []interface{}{value}}
+}
+
+// Gte 生成 column>=?
+func Gte(column string, value interface{}) Cond {
+
+	return expr{column + ">=?", []interface{}{value}}
+}
+
+// Lt 生成 column<?
+func Lt(column string, value interface{}) Cond {
+
+	return expr{column + "<?", []interface{}{value}}
+}
+
+// Lte 生成 column<=?
+func Lte(column string, value interface{}) Cond {
+
+	return expr{column + "<=?", []interface{}{value}}
+}
+
+// Between 生成 column BETWEEN ? AND ?
+func Between(column string, from, to interface{}) Cond {
+
+	return expr{column + " BETWEEN ? AND ?", []interface{}{from, to}}
+}
+
+// Like 生成 column LIKE ?，pattern 中的 % 和 _ 由调用方指定
+func Like(column string, pattern interface{}) Cond {
+
+	return expr{column + " LIKE ?", []interface{}{pattern}}
+}
+
+// IsNull 生成 column IS NULL
+func IsNull(column string) Cond {
+
+	return expr{column + " IS NULL", nil}
+}
+
+// IsNotNull 生成 column IS NOT NULL
+func IsNotNull(column string) Cond {
+
+	return expr{column + " IS NOT NULL", nil}
+}
+
+// In 生成 column IN (?,?,...)，values 为切片或数组，每个元素绑定一个参数，也可以是子查询 *ConDB。
+// 超过 1000 项时拆分为 (column IN (...) OR column IN (...))，values 为空时条件不成立
+func In(column string, values interface{}) Cond {
+
+	return inCond{column: column, values: values}
+}
+
+// NotIn 生成 column NOT IN (?,?,...)，超过 1000 项时拆分为 (column NOT IN (...) AND ...)，
+// values 为空时条件恒成立
+func NotIn(column string, values interface{}) Cond {
+
+	return inCond{column: column, values: values, not: true}
+}
+
+type inCond struct {
+	column string
+	values interface{}
+	not    bool
+}
+
+func (c inCond) Build() (string, []interface{}) {
+
+	if sub, ok := c.values.(*ConDB); ok { //子查询
+
+		if c.not {
+			return c.column + " NOT IN ?", []interface{}{sub}
+		}
+		return c.column + " IN ?", []interface{}{sub}
+	}
+
+	args := flatten(c.values)
+	if len(args) == 0 {
+
+		if c.not {
+			return "1=1", nil
+		}
+		return "1=0", nil
+	}
+
+	op, join := " IN (", " OR "
+	if c.not {
+		op, join = " NOT IN (", " AND "
+	}
+
+	parts := make([]string, 0, (len(args)+maxInItems-1)/maxInItems)
+	for start := 0; start < len(args); start += maxInItems {
+
+		end := start + maxInItems
+		if end > len(args) {
+			end = len(args)
+		}
+		parts = append(parts, c.column+op+strings.TrimSuffix(strings.Repeat("?,", end-start), ",")+")")
+	}
+
+	if len(parts) == 1 {
+		return parts[0], args
+	}
+	return "(" + strings.Join(parts, join) + ")", args
+}
+
+// flatten 把切片或数组展开为参数列表，[]byte 和其它类型作为单个参数
+func flatten(values interface{}) []interface{} {
+
+	if values == nil {
+		return nil
+	}
+	if list, ok := values.([]interface{}); ok {
+		return list
+	}
+
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{values}
+	}
+
+	args := make([]interface{}, v.Len())
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+	return args
+}
+
+// Filter 添加条件，多个条件以 AND 连接，如 db.Filter(oram.Eq("status", 1), oram.In("userid", ids))
+func (m *ConDB) Filter(conds ...Cond) *ConDB {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+
+	for _, c := range conds {
+
+		query, args := c.Build()
+		db.Condition = append(db.Condition, map[string]interface{}{"query": query, "args": args})
+	}
+	return db
+}
+
+// And 以 AND 连接条件，结果带括号，可以嵌套在其它条件中
+func And(conds ...Cond) Cond {
+
+	return group{op: "AND", conds: conds}
+}
+
+// Or 以 OR 连接条件，结果带括号，如 oram.Or(oram.Eq("a", 1), oram.And(oram.Eq("b", 2), oram.Eq("c", 3)))
+func Or(conds ...Cond) Cond {
+
+	return group{op: "OR", conds: conds}
+}
+
+// Not 生成 NOT (cond)
+func Not(cond Cond) Cond {
+
+	return not{cond}
+}
+
+type group struct {
+	op    string
+	conds []Cond
+}
+
+func (g group) Build() (string, []interface{}) {
+
+	if len(g.conds) == 0 {
+
+		if g.op == "OR" {
+			return "1=0", nil
+		}
+		return "1=1", nil
+	}
+	if len(g.conds) == 1 {
+		return g.conds[0].Build()
+	}
+
+	//AND 的优先级高于 OR，AND 中含 OR 的条件需要加括号，OR 中含 AND 的条件加括号便于阅读
+	other := "OR"
+	if g.op == "OR" {
+		other = "AND"
+	}
+
+	parts := make([]string, len(g.conds))
+	var args []interface{}
+	for i, c := range g.conds {
+
+		query, values := c.Build()
+		if topLevel(query, other) {
+			query = "(" + query + ")"
+		}
+		parts[i] = query
+		args = append(args, values...)
+	}
+	return "(" + strings.Join(parts, " "+g.op+" ") + ")", args
+}
+
+type not struct {
+	cond Cond
+}
+
+func (n not) Build() (string, []interface{}) {
+
+	query, args := n.cond.Build()
+	return "NOT (" + query + ")", args
+}
+
+// topLevel 判断 query 在括号和字符串常量之外是否含有关键字 keyword
+func topLevel(query, keyword string) bool {
+
+	depth := 0
+	for i := 0; i < len(query); i++ {
+
+		switch c := query[i]; {
+		case c == '\'':
+			for i++; i < len(query) && query[i] != '\''; i++ {
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (i == 0 || !isIdent(query[i-1]) && !isDigit(query[i-1])):
+			end := i + len(keyword)
+			if end <= len(query) && strings.EqualFold(query[i:end], keyword) &&
+				(end == len(query) || !isIdent(query[end]) && !isDigit(query[end])) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package oram
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildSqlGrouping(t *testing.T) {
+
+	db, _ := newFakeDB(Oracle)
+
+	tests := []struct {
+		name  string
+		q     *ConDB
+		where string
+		args  []interface{}
+	}{
+		{
+			"or before and",
+			db.Table("t").Where("a=?", 1).Or("b=?", 2).Where("c=?", 3),
+			" WHERE (a=:1 OR b=:2) AND c=:3",
+			[]interface{}{1, 2, 3},
+		},
+		{
+			"or inside where",
+			db.Table("t").Where("a=? OR b=?", 1, 2).Where("c=?", 3),
+			" WHERE (a=:1 OR b=:2) AND c=:3",
+			[]interface{}{1, 2, 3},
+		},
+		{
+			"and after or",
+			db.Table("t").Where("a=?", 1).Where("b=?", 2).Or("c=?", 3),
+			" WHERE (a=:1 AND b=:2) OR c=:3",
+			[]interface{}{1, 2, 3},
+		},
+		{
+			"nested groups",
+			db.Table("t").Where("a=?", 1).Filter(Or(Eq("b", 2), And(Eq("c", 3), Ne("d", 4))), Not(In("e", []int{5, 6}))),
+			" WHERE a=:1 AND (b=:2 OR (c=:3 AND d<>:4)) AND NOT (e IN (:5,:6))",
+			[]interface{}{1, 2, 3, 4, 5, 6},
+		},
+		{
+			"empty groups",
+			db.Table("t").Filter(Or(), And()),
+			" WHERE 1=0 AND 1=1",
+			[]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+
+		where := tt.q.buildSql()
+		if where != tt.where {
+			t.Errorf("%s: where = %q, want %q", tt.name, where, tt.where)
+		}
+		if len(tt.q.params) != len(tt.args) || len(tt.args) > 0 && !reflect.DeepEqual(tt.q.params, tt.args) {
+			t.Errorf("%s: args = %v, want %v", tt.name, tt.q.params, tt.args)
+		}
+	}
+}
+
+func TestInChunking(t *testing.T) {
+
+	values := func(n int) []int {
+
+		v := make([]int, n)
+		for i := range v {
+			v[i] = i
+		}
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		cond   Cond
+		parts  []int
+		join   string
+		prefix string
+	}{
+		{"one chunk", In("id", values(1000)), []int{1000}, "", "id IN ("},
+		{"three chunks", In("id", values(2500)), []int{1000, 1000, 500}, " OR ", "(id IN ("},
+		{"not in", NotIn("id", values(1001)), []int{1000, 1}, " AND ", "(id NOT IN ("},
+	}
+	for _, tt := range tests {
+
+		query, args := tt.cond.Build()
+
+		total := 0
+		for _, n := range tt.parts {
+			total += n
+		}
+		if len(args) != total {
+			t.Errorf("%s: %d args, want %d", tt.name, len(args), total)
+		}
+		if !strings.HasPrefix(query, tt.prefix) {
+			t.Errorf("%s: query starts with %.20q, want %q", tt.name, query, tt.prefix)
+		}
+
+		parts := []string{query}
+		if tt.join != "" {
+			parts = strings.Split(strings.Trim(query, "()"), tt.join)
+		}
+		if len(parts) != len(tt.parts) {
+			t.Fatalf("%s: %d parts, want %d", tt.name, len(parts), len(tt.parts))
+		}
+		for i, p := range parts {
+			if n := strings.Count(p, "?"); n != tt.parts[i] {
+				t.Errorf("%s: part %d has %d placeholders, want %d", tt.name, i, n, tt.parts[i])
+			}
+		}
+	}
+
+	empty := []struct {
+		cond Cond
+		want string
+	}{
+		{In("id", []int{}), "1=0"},
+		{NotIn("id", nil), "1=1"},
+	}
+	for _, tt := range empty {
+
+		if query, args := tt.cond.Build(); query != tt.want || len(args) != 0 {
+			t.Errorf("Build() = %q %v, want %q", query, args, tt.want)
+		}
+	}
+}
+
+func TestInChunkingNumbering(t *testing.T) {
+
+	db, _ := newFakeDB(Oracle)
+
+	q := db.Table("t").Where("a=?", "x").Filter(In("id", make([]int, 1500)))
+	where := q.buildSql()
+
+	if !strings.Contains(where, ":1000,:1001)") || !strings.Contains(where, " OR id IN (:1002,") || !strings.HasSuffix(where, ":1501))") {
+		t.Errorf("where = %.60q...%q", where, where[len(where)-20:])
+	}
+	if len(q.params) != 1501 {
+		t.Errorf("%d params, want 1501", len(q.params))
+	}
+}
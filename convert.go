@@ -0,0 +1,234 @@
+package oram
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Converter 在数据库列值和字段类型之间转换，用于映射 Money、Status 等自定义类型
+type Converter interface {
+	// FromDB 把驱动返回的列值转换为字段类型的值，src 为 nil 表示 NULL
+	FromDB(src interface{}) (interface{}, error)
+	// ToDB 把字段值转换为绑定参数
+	ToDB(v interface{}) (driver.Value, error)
+}
+
+var (
+	converterMu sync.RWMutex
+	converters  = make(map[reflect.Type]Converter)
+
+	timeLayouts  = []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02"}
+	timeLocation = time.Local
+
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// RegisterConverter 注册类型 t 的转换器，读取和写入该类型的字段时使用，c 为 nil 时取消注册
+func RegisterConverter(t reflect.Type, c Converter) {
+
+	converterMu.Lock()
+	defer converterMu.Unlock()
+
+	if c == nil {
+		delete(converters, t)
+		return
+	}
+	converters[t] = c
+}
+
+func getConverter(t reflect.Type) Converter {
+
+	if t == nil {
+		return nil
+	}
+
+	converterMu.RLock()
+	defer converterMu.RUnlock()
+
+	return converters[t]
+}
+
+// SetTimeLayouts 设置字符串列转换为 time.Time 时使用的时区和格式，按顺序尝试，
+// 默认为 time.Local 和 "2006-01-02 15:04:05"
+func SetTimeLayouts(loc *time.Location, layouts ...string) {
+
+	converterMu.Lock()
+	defer converterMu.Unlock()
+
+	if loc != nil {
+		timeLocation = loc
+	}
+	if len(layouts) > 0 {
+		timeLayouts = layouts
+	}
+}
+
+func parseTime(value string) (time.Time, error) {
+
+	converterMu.RLock()
+	layouts, loc := timeLayouts, timeLocation
+	converterMu.RUnlock()
+
+	var err error
+	for _, layout := range layouts {
+
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// converterValue 延迟调用 Converter.ToDB，转换错误在执行语句时返回
+type converterValue struct {
+	c Converter
+	v interface{}
+}
+
+func (c converterValue) Value() (driver.Value, error) {
+
+	return c.c.ToDB(c.v)
+}
+
+// convertFrom 把驱动返回的列值转换为类型 t 的值
+func convertFrom(t reflect.Type, src interface{}) (reflect.Value, error) {
+
+	if c := getConverter(t); c != nil {
+
+		v, err := c.FromDB(src)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		return assignable(reflect.ValueOf(v), t)
+	}
+
+	switch v := src.(type) {
+	case nil:
+		return reflect.Zero(t), nil
+	case []byte:
+		return conversionType(string(v), t)
+	case string:
+		return conversionType(v, t)
+	}
+	return assignable(reflect.ValueOf(src), t)
+}
+
+func assignable(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	vk, tk := v.Kind(), t.Kind()
+	if v.Type().ConvertibleTo(t) && (isNumber(vk) && isNumber(tk) || vk == tk) {
+		return v.Convert(t), nil
+	}
+	if tk == reflect.Bool && isNumber(vk) {
+		return reflect.ValueOf(v.Convert(reflect.TypeOf(float64(0))).Float() != 0).Convert(t), nil
+	}
+	if tk == reflect.String {
+		return reflect.ValueOf(fmt.Sprintf("%v", v.Interface())).Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("can't convert %s to %s", v.Type(), t)
+}
+
+func isNumber(k reflect.Kind) bool {
+
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// conversionType 把字符串形式的列值转换为类型 t 的值，支持已注册的转换器、
+// 数值、bool、time.Time、time.Duration 以及以它们为底层类型的自定义类型
+func conversionType(value string, t reflect.Type) (reflect.Value, error) {
+
+	if c := getConverter(t); c != nil {
+		return convertFrom(t, value)
+	}
+
+	if t == timeType {
+
+		if value == "" {
+			return reflect.Zero(t), nil
+		}
+		buf, err := parseTime(value)
+		return reflect.ValueOf(buf), err
+	}
+
+	if t == durationType {
+
+		if d, err := time.ParseDuration(value); err == nil {
+			return reflect.ValueOf(d), nil
+		}
+	}
+
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.String {
+
+		v.SetString(value)
+		return v, nil
+	}
+	value = strings.TrimSpace(value)
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			return v, nil
+		}
+		buf, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+
+			//Oracle NUMBER 可能以 1.0 的形式返回
+			f, ferr := strconv.ParseFloat(value, 64)
+			if ferr != nil || f != float64(int64(f)) {
+				return v, err
+			}
+			buf = int64(f)
+		}
+		v.SetInt(buf)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			return v, nil
+		}
+		buf, err := strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(buf)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			return v, nil
+		}
+		buf, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(buf)
+	case reflect.Bool:
+		if value == "" {
+			return v, nil
+		}
+		buf, err := strconv.ParseBool(value)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(buf)
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return v, errors.New("unsupported type " + t.String())
+		}
+		v.SetBytes([]byte(value))
+	default:
+		return v, errors.New("unsupported type " + t.String())
+	}
+	return v, nil
+}
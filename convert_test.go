@@ -0,0 +1,125 @@
+package oram
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type convertStatus int
+
+// convertMoney 以分为单位，数据库中为 "12.34" 形式的字符串
+type convertMoney int64
+
+type moneyConverter struct{}
+
+func (moneyConverter) FromDB(src interface{}) (interface{}, error) {
+
+	var s string
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return nil, fmt.Errorf("unexpected %T", src)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return convertMoney(f*100 + 0.5), err
+}
+
+func (moneyConverter) ToDB(v interface{}) (driver.Value, error) {
+
+	m := v.(convertMoney)
+	return fmt.Sprintf("%d.%02d", m/100, m%100), nil
+}
+
+func TestConversionType(t *testing.T) {
+
+	SetTimeLayouts(time.UTC, "2006-01-02 15:04:05", "2006-01-02")
+	defer SetTimeLayouts(time.Local, "2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02")
+
+	tests := []struct {
+		value string
+		want  interface{}
+		err   bool
+	}{
+		{"12", int64(12), false},
+		{"12.0", 12, false},
+		{"12.5", 0, true},
+		{" 7 ", int32(7), false},
+		{"", 0, false},
+		{"3", uint8(3), false},
+		{"1.25", 1.25, false},
+		{"true", true, false},
+		{"1", true, false},
+		{"x", false, true},
+		{"abc", "abc", false},
+		{"abc", []byte("abc"), false},
+		{"2", convertStatus(2), false},
+		{"1m30s", 90 * time.Second, false},
+		{"2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, false},
+		{"x", struct{}{}, true},
+	}
+	for _, tt := range tests {
+
+		v, err := conversionType(tt.value, reflect.TypeOf(tt.want))
+		if (err != nil) != tt.err {
+			t.Errorf("conversionType(%q, %T) err = %v", tt.value, tt.want, err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(v.Interface(), tt.want) {
+			t.Errorf("conversionType(%q, %T) = %#v, want %#v", tt.value, tt.want, v.Interface(), tt.want)
+		}
+	}
+}
+
+type convertOrder struct {
+	Id     int64         `db:"id"`
+	Amount convertMoney  `db:"amount"`
+	Status convertStatus `db:"status"`
+}
+
+func TestRegisterConverter(t *testing.T) {
+
+	typ := reflect.TypeOf(convertMoney(0))
+	RegisterConverter(typ, moneyConverter{})
+	defer RegisterConverter(typ, nil)
+
+	db, d := newFakeDB(MySQL)
+	d.results = []*fakeRows{{
+		cols: []string{"id", "amount", "status"},
+		data: [][]driver.Value{{int64(1), []byte("12.34"), int64(2)}, {int64(2), nil, "3"}},
+	}}
+
+	var list []convertOrder
+	if err := db.Table("tb_order").Find(&list).Err; err != nil {
+		t.Fatal(err)
+	}
+	want := []convertOrder{{1, 1234, 2}, {2, 0, 3}}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("Find = %+v, want %+v", list, want)
+	}
+
+	//写入时由 ToDB 转换
+	if err := db.Table("tb_order").Select("amount").Upsert(&convertOrder{Id: 1, Amount: 505}); err != nil {
+		t.Fatal(err)
+	}
+	s := d.statements()
+	if got, want := s[len(s)-1], "[5.05 1 0]"; got[len(got)-len(want):] != want {
+		t.Errorf("statement = %q, want args %s", got, want)
+	}
+
+	//取消注册后按底层类型转换
+	RegisterConverter(typ, nil)
+	if v, err := convertFrom(typ, []byte("7")); err != nil || v.Interface() != convertMoney(7) {
+		t.Errorf("convertFrom after unregister = %v, %v", v, err)
+	}
+}
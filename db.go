@@ -2,6 +2,7 @@ package oram
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
@@ -9,14 +10,17 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
-	"time"
-	"unsafe"
 )
 
 type SqlExecutor interface {
 	Model(class interface{}) *ConDB
 	Table(name string) *ConDB
 	Where(query string, values ...interface{}) *ConDB
+	Filter(conds ...Cond) *ConDB
+	Join(table, on string, args ...interface{}) *ConDB
+	LeftJoin(table, on string, args ...interface{}) *ConDB
+	RightJoin(table, on string, args ...interface{}) *ConDB
+	From(sub *ConDB, alias string) *ConDB
 	Maps(maps map[string]interface{}) *ConDB
 	Or(query string, values ...interface{}) *ConDB
 	IN(key string, value string) *ConDB
@@ -43,14 +47,19 @@ type SqlExecutor interface {
 	FindById(out, id interface{}) error
 	IsExit() (bool, error)
 
+	WithContext(ctx context.Context) *ConDB
+
 	TxBegin() *ConDB
+	TxBeginOpts(ctx context.Context, opts *sql.TxOptions) *ConDB
 	Tx(tx *sql.Tx) *ConDB
+	Transaction(fn func(tx *ConDB) error) error
+	TransactionOpts(ctx context.Context, opts *sql.TxOptions, fn func(tx *ConDB) error) error
 	Commit() error
 	Rollback() error
 	GetForUpdate(out interface{}) error
 
 	Exec(sql string, args ...interface{}) (sql.Result, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
+	QueryRow(query string, args ...interface{}) *Row
 	QueryRows(query string, args ...interface{}) (*sql.Rows, error)
 
 	QueryMap(query string, args ...interface{}) (map[string]string, error)
@@ -83,40 +92,141 @@ type ConDB struct {
 	Result       sql.Result
 	LastInsertId int64
 	Idx          int
+	ctx          context.Context
+	dialect      Dialect
+	model        interface{}
+	returning    []string
+	txs          *txState
+	conf         *config
+	alias        string
+	joins        []join
+	joinParams   []interface{}
+	source       *ConDB
 }
 
-var logger SqlLogger
-var logPrefix string
+// NewDB 使用指定的数据库方言创建 ConDB，dialect 为 nil 时使用 Oracle
+func NewDB(db *sql.DB, dialect Dialect) *ConDB {
+
+	return &ConDB{Db: db, dialect: dialect, conf: &config{}}
+}
 
 type SqlLogger interface {
 	Printf(format string, v ...interface{})
 }
 
+// TraceOn 把日志以文本形式输出到 log，等同于 SetLogger
 func (m *ConDB) TraceOn(prefix string, log SqlLogger) {
-	logger = log
-	if prefix == "" {
-		logPrefix = prefix
-	} else {
-		logPrefix = fmt.Sprintf("%s ", prefix)
+
+	if prefix != "" {
+		prefix = fmt.Sprintf("%s ", prefix)
 	}
+	m.SetLogger(printfLogger{prefix: prefix, out: log})
 }
 
 // TraceOff turns off tracing. It is idempotent.
 func (m *ConDB) TraceOff() {
-	logger = nil
-	logPrefix = ""
+
+	m.SetLogger(nil)
+}
+
+// trace 记录执行语句之外的错误，语句本身由 logStatement 在执行后记录
+func (m *ConDB) trace(msg string, args ...interface{}) {
+
+	logger, _ := m.getLogger()
+	if logger == nil {
+		return
+	}
+	if len(args) == 0 {
+		logger.Log(m.context(), LevelError, msg)
+		return
+	}
+	logger.Log(m.context(), LevelError, msg, "error", argsToStr(args...))
+}
+
+// SetDialect 设置数据库方言，全局生效初始化设置一次
+func (m *ConDB) SetDialect(dialect Dialect) {
+
+	m.dialect = dialect
+}
+
+func (m *ConDB) getDialect() Dialect {
+
+	if m.dialect == nil {
+		return Oracle
+	}
+	return m.dialect
+}
+
+func (m *ConDB) context() context.Context {
+
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+func (m *ConDB) execContext(query string, args ...interface{}) (sql.Result, error) {
+
+	stmt := m.run(query, args, func(s *Statement) error {
+
+		var err error
+		if m.tx == nil {
+			s.result, err = m.Db.ExecContext(s.Context, s.SQL, plainArgs(s.Args)...)
+		} else {
+			s.result, err = m.tx.ExecContext(s.Context, s.SQL, plainArgs(s.Args)...)
+		}
+		if err == nil {
+			s.RowsAffected, _ = s.result.RowsAffected()
+		}
+		return err
+	})
+	return stmt.result, stmt.Err
 }
 
-func (m *ConDB) trace(query string, args ...interface{}) {
-	if logger != nil {
-		var margs = argsToStr(args...)
-		logger.Printf("%s%s [%s]", logPrefix, query, margs)
+func (m *ConDB) queryContext(query string, args ...interface{}) (*sql.Rows, error) {
+
+	stmt := m.run(query, args, func(s *Statement) error {
+
+		var err error
+		if m.tx == nil {
+			s.rows, err = m.Db.QueryContext(s.Context, s.SQL, plainArgs(s.Args)...)
+		} else {
+			s.rows, err = m.tx.QueryContext(s.Context, s.SQL, plainArgs(s.Args)...)
+		}
+		return err
+	})
+	if stmt.Err != nil && stmt.rows != nil {
+
+		stmt.rows.Close()
+		return nil, stmt.Err
+	}
+	return stmt.rows, stmt.Err
+}
+
+func (m *ConDB) queryRowContext(query string, args ...interface{}) *Row {
+
+	stmt := m.run(query, args, func(s *Statement) error {
+
+		if m.tx == nil {
+			s.row = m.Db.QueryRowContext(s.Context, s.SQL, plainArgs(s.Args)...)
+		} else {
+			s.row = m.tx.QueryRowContext(s.Context, s.SQL, plainArgs(s.Args)...)
+		}
+		return s.row.Err()
+	})
+	if stmt.Err != nil && (stmt.row == nil || stmt.row.Err() == nil) {
+
+		if stmt.row != nil {
+			stmt.row.Scan() //释放连接
+		}
+		return &Row{err: stmt.Err}
 	}
+	return &Row{row: stmt.row}
 }
 
 func (m *ConDB) clone() *ConDB {
 
-	db := &ConDB{Db: m.Db, parent: m, tx: nil, inCondition: "", query: "", table: "", Condition: nil, field: "*", Offset: 0, Limit: 0, sort: "", group: "", Idx: 0}
+	db := &ConDB{Db: m.Db, dialect: m.dialect, parent: m, tx: m.tx, txs: m.txs, ctx: m.ctx, conf: m.conf, inCondition: "", query: "", table: "", Condition: nil, field: "*", Offset: 0, Limit: 0, sort: "", group: "", Idx: 0}
 	return db
 }
 
@@ -127,12 +237,14 @@ func (m *ConDB) Model(class interface{}) *ConDB {
 
 		db := m.clone()
 		db.table = getTable(class)
+		db.model = class
 		return db
 	} else {
 
 		if m.table == "" {
 			m.table = getTable(class)
 		}
+		m.model = class
 		return m
 	}
 
@@ -168,46 +280,111 @@ func (m *ConDB) Field(field string) *ConDB {
 	return m
 }
 
+// WithContext 绑定 context，之后的查询、执行和事务都使用该 context，
+// 可用于请求取消和超时控制。ctx 携带 TransactionContext 开启的事务时自动加入该事务
+func (m *ConDB) WithContext(ctx context.Context) *ConDB {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+
+	db.ctx = ctx
+	if tx := db.txFromContext(ctx); tx != nil && db.tx == nil {
+
+		db.tx = tx.tx
+		db.txs = tx.txs
+	}
+	return db
+}
+
 func (m *ConDB) TxBegin() *ConDB {
 
-	tx, _ := m.Db.Begin()
+	return m.TxBeginOpts(m.context(), nil)
+}
 
+// TxBeginOpts 按 opts 指定的隔离级别、只读属性开启事务，ctx 取消或超时时事务自动回滚
+func (m *ConDB) TxBeginOpts(ctx context.Context, opts *sql.TxOptions) *ConDB {
+
+	var db *ConDB
 	if m.parent == nil {
-		db := m.clone()
-		db.tx = tx
-		return db
+
+		db = m.clone()
 	} else {
+		db = m
+	}
 
-		m.tx = tx
-		return m
+	db.ctx = ctx
+	db.tx, db.Err = db.Db.BeginTx(ctx, opts)
+	if db.Err != nil {
+		db.trace("begin error:", db.Err)
 	}
+	db.txs = &txState{err: db.Err}
+	return db
 }
 func (m *ConDB) Tx(tx *sql.Tx) *ConDB {
 
 	if m.parent == nil {
 		db := m.clone()
 		db.tx = tx
+		db.txs = &txState{}
 		return db
 	} else {
 
 		m.tx = tx
+		m.txs = &txState{}
 		return m
 	}
 }
 
 func (m *ConDB) Commit() error {
 
-	return m.tx.Commit()
+	if m.tx == nil {
+		return m.noTx()
+	}
+
+	err := m.traceTx("commit", m.tx.Commit)
+	if m.txs != nil {
+
+		if err == nil {
+			m.txs.commit()
+		} else {
+			m.txs.rollback()
+		}
+	}
+	return err
 }
 
 func (m *ConDB) Rollback() error {
 
-	return m.tx.Rollback()
+	if m.tx == nil {
+		return m.noTx()
+	}
+
+	err := m.traceTx("rollback", m.tx.Rollback)
+	if m.txs != nil {
+		m.txs.rollback()
+	}
+	return err
+}
+
+// noTx 返回没有事务时 Commit、Rollback 的错误，TxBegin 失败时为开启事务的错误
+func (m *ConDB) noTx() error {
+
+	if err := m.beginErr(); err != nil {
+		return err
+	}
+	if m.Err != nil {
+		return m.Err
+	}
+	return errors.New("no transaction")
 }
 
 func (m *ConDB) Maps(maps map[string]interface{}) *ConDB {
 
-	i := 1
 	if m.parent == nil {
 		db := m.clone()
 		if maps != nil && len(maps) > 0 {
@@ -216,10 +393,7 @@ func (m *ConDB) Maps(maps map[string]interface{}) *ConDB {
 				if m_type(v) == "string" && v == "" { //忽略空
 					continue
 				}
-				query := fmt.Sprintf("%s=:%d", k, i)
-				db.Where(query, v)
-				i++
-				db.Idx++
+				db.Where(k+"=?", v)
 			}
 
 		}
@@ -232,10 +406,7 @@ func (m *ConDB) Maps(maps map[string]interface{}) *ConDB {
 				if m_type(v) == "string" && v == "" { //忽略空
 					continue
 				}
-				query := fmt.Sprintf("%s=:%d", k, i)
-				m.Where(query, v)
-				i++
-				m.Idx++
+				m.Where(k+"=?", v)
 			}
 		}
 		return m
@@ -352,6 +523,8 @@ func (m *ConDB) Flush(c interface{}) error {
 		db = m
 	}
 
+	registerModel(c)
+
 	s := bytes.Buffer{}
 
 	s.WriteString("UPDATE ")
@@ -375,58 +548,67 @@ func (m *ConDB) Flush(c interface{}) error {
 		mv.Call(nil)
 	}
 
+	if err := db.beforeUpdate(c); err != nil {
+		return err
+	}
+
 	data := toMap(val, typ)
 	buff := bytes.NewBuffer([]byte{})
 
+	key := getModel(typ).keyColumn()
+
 	var id interface{}
 	idx := 1
 	for k, v := range data {
 
-		if k == "id" {
+		if k == key {
 			id = v
 			continue
 		}
 		buff.WriteString(",")
 		buff.WriteString(k)
-		buff.WriteString(fmt.Sprintf("=:%d ", idx))
+		buff.WriteString("=")
+		buff.WriteString(db.getDialect().Placeholder(idx))
+		buff.WriteString(" ")
 
 		//buff.WriteString(parseString(v))
-		db.params = append(db.params, v)
+		db.params = append(db.params, bindValue(v))
 		idx++
 	}
 
 	sql := strings.TrimLeft(buff.String(), `,`)
 
 	s.WriteString(sql)
-	s.WriteString(" where id =:")
-	s.WriteString(fmt.Sprintf("%d ", idx))
+	s.WriteString(" where ")
+	s.WriteString(key)
+	s.WriteString(" =")
+	s.WriteString(db.getDialect().Placeholder(idx))
 
 	//p := getKey(c, "Id")
-	db.params = append(db.params, id)
-
-	db.trace(s.String(), db.params...)
+	db.params = append(db.params, bindValue(id))
 
-	if db.tx == nil {
-		db.Result, db.Err = db.Db.Exec(s.String(), db.params...)
-
-	} else {
-		db.Result, db.Err = db.tx.Exec(s.String(), db.params...)
+	if len(db.returning) > 0 {
 
+		if err := db.execReturning(s.String(), db.params, val); err != nil {
+			return err
+		}
+		return db.afterUpdate(c)
 	}
 
+	db.Result, db.Err = db.execContext(s.String(), db.params...)
+
 	if db.Err != nil {
 
 		return db.Err
 	}
 
-	aff_nums, err := db.Result.RowsAffected()
-	if err == nil {
-		db.trace("RowsAffected num:", aff_nums)
-	} else {
-		db.trace("RowsAffected error:%v", err)
+	if _, err := db.Result.RowsAffected(); err != nil {
+
+		db.trace("RowsAffected error:", err)
+		return err
 	}
 
-	return err
+	return db.afterUpdate(c)
 
 }
 
@@ -449,33 +631,43 @@ func (db *ConDB) Update(field string, values ...interface{}) error {
 
 	params := append(values, db.params...)
 
-	db.trace(s.String(), db.params...)
+	if err := db.beforeUpdate(db.model); err != nil {
+		return err
+	}
 
-	if db.tx == nil {
-		db.Result, db.Err = db.Db.Exec(s.String(), params...)
+	if len(db.returning) > 0 {
 
-	} else {
-		db.Result, db.Err = db.tx.Exec(s.String(), params...)
+		out := reflect.ValueOf(db.model)
+		if out.Kind() != reflect.Ptr {
 
+			db.trace("Returning need Model with a pointer")
+			return errors.New("Returning need Model with a pointer")
+		}
+		if err := db.execReturning(s.String(), params, out); err != nil {
+			return err
+		}
+		return db.afterUpdate(db.model)
 	}
 
+	db.Result, db.Err = db.execContext(s.String(), params...)
+
 	if db.Err != nil {
 
 		return db.Err
 	}
 
 	aff_nums, err := db.Result.RowsAffected()
-	if err == nil {
-		db.trace("RowsAffected num:", aff_nums)
-		if aff_nums == 0 {
+	if err != nil {
 
-			return errors.New("RowsAffected rows is 0")
-		}
-	} else {
-		db.trace("RowsAffected error:%v", err)
+		db.trace("RowsAffected error:", err)
+		return err
 	}
+	if aff_nums == 0 {
 
-	return err
+		return errors.New("RowsAffected rows is 0")
+	}
+
+	return db.afterUpdate(db.model)
 
 }
 
@@ -489,15 +681,7 @@ func (m *ConDB) Exec(sql string, params ...interface{}) (sql.Result, error) {
 		db = m
 	}
 
-	db.trace(sql, params...)
-
-	if db.tx == nil {
-		db.Result, db.Err = db.Db.Exec(sql, params...)
-
-	} else {
-		db.Result, db.Err = db.tx.Exec(sql, params...)
-
-	}
+	db.Result, db.Err = db.execContext(sql, params...)
 
 	return db.Result, db.Err
 
@@ -525,16 +709,16 @@ func (db *ConDB) Delete(i ...interface{}) error {
 	if len(i) > 0 {
 
 		c := i[0]
-		key := reflect.ValueOf(c).Elem().FieldByName("Id")
+		key := getModel(reflect.TypeOf(c)).key
 
-		if !key.IsValid() {
+		if key == nil {
 
 			db.trace("doesn't found key")
 			return errors.New("doesn't found key")
 		}
 		//db1 := db.clone()
-		id := fmt.Sprintf("%d", key)
-		return db.Model(c).Where("id=:1", id).delete()
+		id := key.value(reflect.ValueOf(c).Elem()).Interface()
+		return db.Model(c).Where(key.column+"=?", id).delete()
 
 	} else {
 
@@ -561,33 +745,27 @@ func (db *ConDB) delete() error {
 
 	s.WriteString(db.buildSql())
 
-	db.trace(s.String(), db.params...)
-
-	if db.tx == nil {
-		db.Result, db.Err = db.Db.Exec(s.String(), db.params...)
-
-	} else {
-		db.Result, db.Err = db.tx.Exec(s.String(), db.params...)
-
+	if err := db.beforeDelete(db.model); err != nil {
+		return err
 	}
 
+	db.Result, db.Err = db.execContext(s.String(), db.params...)
+
 	if db.Err != nil {
 
-		db.trace("error:%v", db.Err)
 		return db.Err
 	}
 
-	aff_nums, err := db.Result.RowsAffected()
-	if err == nil {
-		db.trace("RowsAffected num:", aff_nums)
-	} else {
-		db.trace("RowsAffected error:%v", err)
+	if _, err := db.Result.RowsAffected(); err != nil {
+
+		db.trace("RowsAffected error:", err)
+		return err
 	}
 
-	return err
+	return db.afterDelete(db.model)
 }
 
-func into(field string) string {
+func into(d Dialect, field string) string {
 
 	//arry := strings.Split(field, ",")
 
@@ -596,7 +774,7 @@ func into(field string) string {
 	sum := strings.Count(field, "?")
 	for i := 1; i <= sum; i++ {
 
-		str = fmt.Sprintf("%s:%d,", str, i)
+		str = fmt.Sprintf("%s%s,", str, d.Placeholder(i))
 
 	}
 
@@ -604,13 +782,13 @@ func into(field string) string {
 	return vas
 }
 
-func sets(field string) (int, string) {
+func sets(d Dialect, field string) (int, string) {
 	str := strings.Replace(field, ",", "=?,", -1)
 
 	sum := strings.Count(str, "?")
 	for i := 1; i <= sum; i++ {
 
-		str = strings.Replace(str, "?", fmt.Sprintf(":%d", i), 1)
+		str = strings.Replace(str, "?", d.Placeholder(i), 1)
 
 	}
 	return sum, str
@@ -625,29 +803,21 @@ func (m *ConDB) Save(table, field string, key interface{}, args []interface{}) e
 	} else {
 		db = m
 	}
-	idx, ss := sets(field)
+	idx, ss := sets(db.getDialect(), field)
 
-	sql := fmt.Sprintf(`update %s set %s where id = :`, table, ss, idx)
+	sql := fmt.Sprintf(`update %s set %s where id = %s`, table, ss, db.getDialect().Placeholder(idx+1))
 
 	args = append(args, key)
-	db.trace(sql, args...)
-	if db.tx == nil {
-
-		db.Result, db.Err = db.Db.Exec(sql, args...)
-	} else {
-		db.Result, db.Err = db.tx.Exec(sql, args...)
-	}
+	db.Result, db.Err = db.execContext(sql, args...)
 
 	if db.Err != nil {
 
 		return db.Err
 	}
 
-	aff_nums, err := db.Result.RowsAffected()
-	if err == nil {
-		db.trace("RowsAffected num:", aff_nums)
-	} else {
-		db.trace("RowsAffected error:%v", err)
+	_, err := db.Result.RowsAffected()
+	if err != nil {
+		db.trace("RowsAffected error:", err)
 	}
 
 	return err
@@ -664,15 +834,9 @@ func (m *ConDB) Add(table, field string, args []interface{}) error {
 		db = m
 	}
 
-	sql := `insert into ` + table + ` (` + field + `) values (` + into(field) + `)`
+	sql := `insert into ` + table + ` (` + field + `) values (` + into(db.getDialect(), field) + `)`
 
-	db.trace(sql, args...)
-	if db.tx == nil {
-
-		db.Result, db.Err = db.Db.Exec(sql, args...)
-	} else {
-		db.Result, db.Err = db.tx.Exec(sql, args...)
-	}
+	db.Result, db.Err = db.execContext(sql, args...)
 
 	return db.Err
 
@@ -687,6 +851,7 @@ func (m *ConDB) Insert(i interface{}) error {
 	} else {
 		db = m
 	}
+	registerModel(i)
 	s := bytes.Buffer{}
 
 	s.WriteString("INSERT INTO  ")
@@ -698,48 +863,138 @@ func (m *ConDB) Insert(i interface{}) error {
 
 	s.WriteString(db.table)
 
-	s.WriteString(insertSql(db, i))
+	legacyHook(i, "PreInsert")
+	if err := db.beforeInsert(i); err != nil {
+		return err
+	}
+
+	values, args, auto, err := insertSql(db, i)
+	if err != nil {
+
+		db.Err = err
+		return err
+	}
+	s.WriteString(values)
 
-	db.trace(s.String())
+	if len(db.returning) > 0 {
 
-	//var ret sql.Result
-	//var err error
-	if db.tx == nil {
+		if err := db.execReturning(s.String(), args, reflect.ValueOf(i)); err != nil {
+			return err
+		}
+		if key := getModel(reflect.TypeOf(i)).key; auto && db.LastInsertId == 0 && key != nil {
 
-		db.Result, db.Err = db.Db.Exec(s.String())
+			switch key.typ.Kind() {
+			case reflect.Int, reflect.Int32, reflect.Int64:
+				db.LastInsertId = key.value(reflect.ValueOf(i).Elem()).Int()
+			}
+		}
+		return db.afterInsert(i)
+	}
+
+	d := db.getDialect()
+	idKey := getModel(reflect.TypeOf(i)).keyColumn()
+	if auto && d.Sequence(db.table) == "" && d.Returning(idKey) != "" {
+
+		s.WriteString(d.Returning(idKey))
+
+		db.Err = db.queryRowContext(s.String(), args...).Scan(&db.LastInsertId)
 	} else {
-		db.Result, db.Err = db.tx.Exec(s.String())
+
+		db.Result, db.Err = db.execContext(s.String(), args...)
+		if db.Err == nil && auto && d.Sequence(db.table) == "" {
+
+			db.LastInsertId, db.Err = db.Result.LastInsertId()
+		}
 	}
 
 	if db.Err != nil {
 
 		return db.Err
 	}
+	if !auto {
 
-	insID := db.LastInsertId
+		return db.afterInsert(i)
+	}
 
-	db.trace("RowsAffected num:", insID)
+	setId(reflect.ValueOf(i), db.LastInsertId)
 
-	key := reflect.ValueOf(i).Elem().FieldByName("Id")
-	typ, _ := reflect.TypeOf(i).Elem().FieldByName("Id")
+	return db.afterInsert(i)
+}
 
-	tp := typ.Type.Name()
+// setId 把生成的主键写回结构体的 Id 字段
+func setId(v reflect.Value, id int64) {
 
-	if tp == "int" {
+	model := getModel(v.Type())
+	if model.key == nil {
+		return
+	}
 
-		newValue := reflect.ValueOf(int(insID))
-		key.Set(newValue)
-	} else if tp == "int32" {
+	key := model.key.value(v.Elem())
+	switch key.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		key.SetInt(id)
+	}
+}
 
-		newValue := reflect.ValueOf(int32(insID))
-		key.Set(newValue)
-	} else if tp == "int64" {
+// Returning 让 Insert、Flush、Update 通过 RETURNING 子句带回 cols 字段的值，
+// Insert、Flush 写回传入的结构体，Update 写回 Model 传入的结构体指针
+func (m *ConDB) Returning(cols ...string) *ConDB {
 
-		newValue := reflect.ValueOf(insID)
-		key.Set(newValue)
+	if m.parent == nil {
+		db := m.clone()
+		db.returning = cols
+		return db
+	} else {
+
+		m.returning = cols
+		return m
 	}
+}
 
-	return nil
+// execReturning 执行带 RETURNING 子句的写语句，把返回的字段写入 out 指向的结构体
+func (db *ConDB) execReturning(query string, args []interface{}, out reflect.Value) error {
+
+	clause, bind := db.getDialect().ReturningInto(db.returning, len(args))
+	if clause == "" {
+
+		db.trace("dialect doesn't support RETURNING")
+		return errors.New("dialect doesn't support RETURNING")
+	}
+
+	dest := make([]interface{}, len(db.returning))
+	for i, col := range db.returning {
+
+		field := fieldByColumn(out.Elem(), col)
+		if !field.IsValid() {
+
+			db.trace("Returning column not found:", col)
+			return errors.New("Returning column not found: " + col)
+		}
+		dest[i] = field.Addr().Interface()
+	}
+
+	query += clause
+	if bind {
+
+		for _, d := range dest {
+			args = append(args, sql.Out{Dest: d})
+		}
+
+		db.Result, db.Err = db.execContext(query, args...)
+	} else {
+
+		db.Err = db.queryRowContext(query, args...).Scan(dest...)
+	}
+	return db.Err
+}
+
+// fieldByColumn 按 db 标签查找结构体字段，包括匿名字段中的字段
+func fieldByColumn(v reflect.Value, col string) reflect.Value {
+
+	if f := getModel(v.Type()).column(col); f != nil {
+		return f.value(v)
+	}
+	return reflect.Value{}
 }
 
 func (db *ConDB) InsertId() int64 {
@@ -767,20 +1022,22 @@ func (db *ConDB) Page(cur, count int32) *ConDB {
 	return db
 }
 
+// Or 与之前添加的全部条件以 OR 连接，Where(a).Where(b).Or(c) 生成 (a AND b) OR c
 func (db *ConDB) Or(query string, values ...interface{}) *ConDB {
 	if db.parent == nil {
 		return nil
 	}
-	db.OrCondition = append(db.OrCondition, map[string]interface{}{"query": query, "args": values})
+	db.Condition = append(db.Condition, map[string]interface{}{"query": query, "args": values, "or": true})
 	return db
 }
 
+// IN 把 value 直接拼接到语句中，value 不能包含外部输入，绑定参数使用 Filter(In(key, values))
 func (db *ConDB) IN(key string, value string) *ConDB {
 
 	if db.parent == nil {
 		return nil
 	}
-	db.inCondition = key + " IN (" + value + ") "
+	db.inCondition = key + " IN (" + value + ")"
 
 	return db
 }
@@ -815,7 +1072,7 @@ func (db *ConDB) Count(agrs ...interface{}) int64 {
 	if db.parent == nil {
 		return 0
 	}
-	if db.table == "" {
+	if !db.hasTable() {
 		if len(agrs) == 0 {
 			return 0
 		}
@@ -826,7 +1083,7 @@ func (db *ConDB) Count(agrs ...interface{}) int64 {
 	db_sql.WriteString("SELECT count(")
 	db_sql.WriteString(db.field)
 	db_sql.WriteString(") FROM ")
-	db_sql.WriteString(db.table)
+	db_sql.WriteString(db.from())
 
 	db_sql.WriteString(db.buildSql())
 
@@ -835,11 +1092,9 @@ func (db *ConDB) Count(agrs ...interface{}) int64 {
 		db_sql.WriteString(db.group)
 	}
 
-	db.trace(db_sql.String(), db.params...)
-
 	var count int64 = 0
 
-	err := db.Db.QueryRow(db_sql.String(), db.params...).Scan(&count)
+	err := db.queryRowContext(db_sql.String(), db.params...).Scan(&count)
 	if err != nil {
 
 		if err == sql.ErrNoRows {
@@ -860,16 +1115,17 @@ func (db *ConDB) Find(out interface{}) *ConDB {
 	if db.parent == nil {
 		return nil
 	}
-	if db.table == "" {
+	registerModel(out)
+	if !db.hasTable() {
 
 		db.table = getTable(out)
 	}
 
 	sqlStr := bytes.Buffer{}
 	sqlStr.WriteString("SELECT ")
-	sqlStr.WriteString(db.field)
+	sqlStr.WriteString(db.selectFields(out))
 	sqlStr.WriteString(" FROM ")
-	sqlStr.WriteString(db.table)
+	sqlStr.WriteString(db.from())
 
 	sqlStr.WriteString(db.buildSql())
 
@@ -884,16 +1140,12 @@ func (db *ConDB) Find(out interface{}) *ConDB {
 	var sql string
 	if db.Limit > 0 {
 
-		//ls := fmt.Sprintf(" limit %d,%d", db.Offset, db.Limit)
-		//sqlStr.WriteString(ls)
-		sql = fmt.Sprintf("SELECT * FROM (SELECT TT.*, ROWNUM AS ROWNO FROM (%s) TT  WHERE ROWNUM <= %d) TABLE_ALIAS WHERE TABLE_ALIAS.ROWNO >= %d", sqlStr.String(), db.Limit, db.Offset)
+		sql = db.getDialect().Paginate(sqlStr.String(), db.Offset, db.Limit-db.Offset)
 	} else {
 		sql = sqlStr.String()
 	}
 
-	db.trace(sql, db.params...)
-
-	rows, err := db.Db.Query(sql, db.params...)
+	rows, err := db.queryContext(sql, db.params...)
 	if err != nil {
 
 		db.Err = err
@@ -902,6 +1154,9 @@ func (db *ConDB) Find(out interface{}) *ConDB {
 	defer rows.Close()
 
 	db.Err = rowsToList(rows, out)
+	if db.Err == nil {
+		db.Err = db.afterFind(out)
+	}
 	//_, db.Err = db.dbmap.Select(out, sql.String())
 	return db
 }
@@ -910,20 +1165,20 @@ func (db *ConDB) FindAll(out interface{}) *ConDB {
 	if db.parent == nil {
 		return nil
 	}
-	if db.table == "" {
+	registerModel(out)
+	if !db.hasTable() {
 
 		db.table = getTable(out)
 	}
 
 	sqlStr := bytes.Buffer{}
 	sqlStr.WriteString("SELECT ")
-	sqlStr.WriteString(db.field)
+	sqlStr.WriteString(db.selectFields(out))
 	sqlStr.WriteString(" FROM ")
-	sqlStr.WriteString(db.table)
-
-	db.trace(sqlStr.String(), nil)
+	sqlStr.WriteString(db.from())
 
-	rows, err := db.Db.Query(sqlStr.String())
+	//不拼接条件，只带上子查询和连接的参数
+	rows, err := db.queryContext(sqlStr.String(), db.joinParams...)
 	if err != nil {
 
 		db.Err = err
@@ -932,6 +1187,9 @@ func (db *ConDB) FindAll(out interface{}) *ConDB {
 	defer rows.Close()
 
 	db.Err = rowsToList(rows, out)
+	if db.Err == nil {
+		db.Err = db.afterFind(out)
+	}
 
 	//_, db.Err = db.dbmap.Select(out, sql.String())
 	return db
@@ -941,7 +1199,7 @@ func (db *ConDB) Query() (map[string]string, error) {
 	if db.parent == nil {
 		return nil, errors.New("not found ConDB")
 	}
-	if db.table == "" {
+	if !db.hasTable() {
 
 		return nil, errors.New("not found table")
 	}
@@ -950,31 +1208,25 @@ func (db *ConDB) Query() (map[string]string, error) {
 	sqlStr.WriteString("SELECT ")
 	sqlStr.WriteString(db.field)
 	sqlStr.WriteString(" FROM ")
-	sqlStr.WriteString(db.table)
+	sqlStr.WriteString(db.from())
 
-	sql := db.buildSql()
-	sqlStr.WriteString(sql)
+	sqlStr.WriteString(db.buildSql())
 
 	if db.group != "" {
 
 		sqlStr.WriteString(db.group)
 	}
-	if sql != "" {
-		sqlStr.WriteString(" and rownum <=1")
-	} else {
-		sqlStr.WriteString("  rownum <=1")
-	}
 
-	db.trace(sqlStr.String(), db.params...)
+	sql := db.getDialect().LimitOne(sqlStr.String())
 
-	rows, err := db.Db.Query(sqlStr.String(), db.params...)
+	rows, err := db.queryContext(sql, db.params...)
 	if err != nil {
 
 		return nil, err
 	}
 	defer rows.Close()
 
-	return rowsToMap(rows)
+	return rowsToMap(rows, len(db.joins) > 0)
 
 }
 func (db *ConDB) List() ([]map[string]string, error) {
@@ -982,7 +1234,7 @@ func (db *ConDB) List() ([]map[string]string, error) {
 	if db.parent == nil {
 		return nil, errors.New("not found ConDB")
 	}
-	if db.table == "" {
+	if !db.hasTable() {
 
 		return nil, errors.New("not found table")
 	}
@@ -991,7 +1243,7 @@ func (db *ConDB) List() ([]map[string]string, error) {
 	sqlStr.WriteString("SELECT ")
 	sqlStr.WriteString(db.field)
 	sqlStr.WriteString(" FROM ")
-	sqlStr.WriteString(db.table)
+	sqlStr.WriteString(db.from())
 
 	sqlStr.WriteString(db.buildSql())
 
@@ -1007,23 +1259,19 @@ func (db *ConDB) List() ([]map[string]string, error) {
 	var sql string
 	if db.Limit > 0 {
 
-		//ls := fmt.Sprintf(" limit %d,%d", db.Offset, db.Limit)
-		//sqlStr.WriteString(ls)
-		sql = fmt.Sprintf("SELECT * FROM (SELECT TT.*, ROWNUM AS ROWNO FROM (%s) TT  WHERE ROWNUM <= %d) TABLE_ALIAS WHERE TABLE_ALIAS.ROWNO >= %d", sqlStr.String(), db.Limit, db.Offset)
+		sql = db.getDialect().Paginate(sqlStr.String(), db.Offset, db.Limit-db.Offset)
 	} else {
 		sql = sqlStr.String()
 	}
 
-	db.trace(sqlStr.String(), db.params...)
-
-	rows, err := db.Db.Query(sql, db.params...)
+	rows, err := db.queryContext(sql, db.params...)
 	if err != nil {
 
 		return nil, err
 	}
 	defer rows.Close()
 
-	return rowsToMaps(rows)
+	return rowsToMaps(rows, len(db.joins) > 0)
 }
 
 func (db *ConDB) SelectInt(field string) int64 {
@@ -1033,20 +1281,13 @@ func (db *ConDB) SelectInt(field string) int64 {
 	db_sql.WriteString("SELECT ")
 	db_sql.WriteString(field)
 	db_sql.WriteString(" FROM ")
-	db_sql.WriteString(db.table)
-
-	sql := db.buildSql()
-	db_sql.WriteString(sql)
+	db_sql.WriteString(db.from())
 
-	if sql != "" {
-		db_sql.WriteString(" and rownum <=1")
-	} else {
-		db_sql.WriteString("  rownum <=1")
-	}
+	db_sql.WriteString(db.buildSql())
 
-	db.trace(db_sql.String(), db.params...)
+	sql := db.getDialect().LimitOne(db_sql.String())
 
-	db.Err = db.Db.QueryRow(db_sql.String(), db.params...).Scan(&out)
+	db.Err = db.queryRowContext(sql, db.params...).Scan(&out)
 	return out
 }
 
@@ -1057,14 +1298,12 @@ func (db *ConDB) SumInt(field string) int64 {
 	db_sql.WriteString("SELECT ")
 	db_sql.WriteString(field)
 	db_sql.WriteString(" FROM ")
-	db_sql.WriteString(db.table)
+	db_sql.WriteString(db.from())
 
 	sql := db.buildSql()
 	db_sql.WriteString(sql)
 
-	db.trace(db_sql.String(), db.params...)
-
-	db.Err = db.Db.QueryRow(db_sql.String(), db.params...).Scan(&out)
+	db.Err = db.queryRowContext(db_sql.String(), db.params...).Scan(&out)
 	return out
 }
 func (db *ConDB) SelectStr(field string) string {
@@ -1074,20 +1313,13 @@ func (db *ConDB) SelectStr(field string) string {
 	db_sql.WriteString("SELECT ")
 	db_sql.WriteString(field)
 	db_sql.WriteString(" FROM ")
-	db_sql.WriteString(db.table)
-
-	sql := db.buildSql()
-	db_sql.WriteString(sql)
+	db_sql.WriteString(db.from())
 
-	if sql != "" {
-		db_sql.WriteString(" and rownum <=1")
-	} else {
-		db_sql.WriteString("  rownum <=1")
-	}
+	db_sql.WriteString(db.buildSql())
 
-	db.trace(db_sql.String(), db.params...)
+	sql := db.getDialect().LimitOne(db_sql.String())
 
-	db.Err = db.Db.QueryRow(db_sql.String(), db.params...).Scan(&out)
+	db.Err = db.queryRowContext(sql, db.params...).Scan(&out)
 	return out
 }
 func (db *ConDB) QueryField(field string, out interface{}) error {
@@ -1096,13 +1328,11 @@ func (db *ConDB) QueryField(field string, out interface{}) error {
 	db_sql.WriteString("SELECT ")
 	db_sql.WriteString(field)
 	db_sql.WriteString(" FROM ")
-	db_sql.WriteString(db.table)
+	db_sql.WriteString(db.from())
 
 	db_sql.WriteString(db.buildSql())
 
-	db.trace(db_sql.String(), db.params...)
-
-	rows, err := db.Db.Query(db_sql.String(), db.params...)
+	rows, err := db.queryContext(db_sql.String(), db.params...)
 	if err != nil {
 
 		return err
@@ -1131,20 +1361,13 @@ func (db *ConDB) IsExit() (bool, error) {
 
 	db_sql := bytes.Buffer{}
 	db_sql.WriteString("SELECT 1  FROM ")
-	db_sql.WriteString(db.table)
-
-	sql := db.buildSql()
-	db_sql.WriteString(sql)
+	db_sql.WriteString(db.from())
 
-	if sql != "" {
-		db_sql.WriteString(" and rownum =1")
-	} else {
-		db_sql.WriteString("  rownum =1")
-	}
+	db_sql.WriteString(db.buildSql())
 
-	db.trace(db_sql.String(), db.params...)
+	sql := db.getDialect().LimitOne(db_sql.String())
 
-	db.Err = db.Db.QueryRow(db_sql.String(), db.params...).Scan(&out)
+	db.Err = db.queryRowContext(sql, db.params...).Scan(&out)
 
 	if db.Err != nil && db.Err.Error() == "sql: no rows in result set" {
 
@@ -1159,6 +1382,7 @@ func (db *ConDB) FindById(out, id interface{}) error {
 	if db.parent == nil {
 		DB = db.clone()
 	}
+	registerModel(out)
 	if DB.table == "" {
 
 		DB.table = getTable(out)
@@ -1169,20 +1393,22 @@ func (db *ConDB) FindById(out, id interface{}) error {
 	sqlStr.WriteString(DB.field)
 	sqlStr.WriteString(" FROM ")
 	sqlStr.WriteString(DB.table)
-	sqlStr.WriteString(" WHERE id=:1")
+	sqlStr.WriteString(" WHERE ")
+	sqlStr.WriteString(getModel(reflect.TypeOf(out)).keyColumn())
+	sqlStr.WriteString("=")
+	sqlStr.WriteString(DB.getDialect().Placeholder(1))
 
-	DB.trace(sqlStr.String(), id)
-	rows, err := DB.Db.Query(sqlStr.String(), id)
+	rows, err := DB.queryContext(sqlStr.String(), id)
 	if err != nil {
 
 		return err
 	}
 	defer rows.Close()
 
-	//return rowsToStruct(rows, out)
-	mp, err := rowsToMap(rows)
-	StructOfMap(out, mp)
-	return err
+	if err := rowsToStruct(rows, out); err != nil {
+		return err
+	}
+	return DB.afterFind(out)
 
 }
 func (db *ConDB) Get(out interface{}) error {
@@ -1190,52 +1416,46 @@ func (db *ConDB) Get(out interface{}) error {
 	if db.parent == nil {
 		return nil
 	}
-	if db.table == "" {
+	registerModel(out)
+	if !db.hasTable() {
 
 		db.table = getTable(out)
 	}
 
 	sqlStr := bytes.Buffer{}
 	sqlStr.WriteString("SELECT ")
-	sqlStr.WriteString(db.field)
+	sqlStr.WriteString(db.selectFields(out))
 	sqlStr.WriteString(" FROM ")
-	sqlStr.WriteString(db.table)
+	sqlStr.WriteString(db.from())
 
-	sql := db.buildSql()
-	sqlStr.WriteString(sql)
+	sqlStr.WriteString(db.buildSql())
 
 	if db.group != "" {
 
 		sqlStr.WriteString(db.group)
 	}
 
-	if sql != "" {
-		sqlStr.WriteString(" and rownum =1")
-	} else {
-		sqlStr.WriteString("  rownum =1")
-	}
-
-	db.trace(sqlStr.String(), db.params...)
+	sql := db.getDialect().LimitOne(sqlStr.String())
 
 	t := reflect.TypeOf(out)
 	kind := t.Elem().Kind()
 
 	if reflect.Struct == kind {
 
-		rows, err := db.Db.Query(sqlStr.String(), db.params...)
+		rows, err := db.queryContext(sql, db.params...)
 		if err != nil {
 
 			return err
 		}
 		defer rows.Close()
 
-		//return rowsToStruct(rows, out)
-		mp, err := rowsToMap(rows)
-		StructOfMap(out, mp)
-		return err
+		if err := rowsToStruct(rows, out); err != nil {
+			return err
+		}
+		return db.afterFind(out)
 	}
 
-	db.Err = db.Db.QueryRow(sqlStr.String(), db.params...).Scan(out)
+	db.Err = db.queryRowContext(sql, db.params...).Scan(out)
 	return db.Err
 
 }
@@ -1245,24 +1465,23 @@ func (db *ConDB) GetForUpdate(out interface{}) error {
 	if db.parent == nil {
 		return nil
 	}
-	if db.table == "" {
+	registerModel(out)
+	if !db.hasTable() {
 
 		db.table = getTable(out)
 	}
 
 	sqlStr := bytes.Buffer{}
 	sqlStr.WriteString("SELECT ")
-	sqlStr.WriteString(db.field)
+	sqlStr.WriteString(db.selectFields(out))
 	sqlStr.WriteString(" FROM ")
-	sqlStr.WriteString(db.table)
+	sqlStr.WriteString(db.from())
 
 	sqlStr.WriteString(db.buildSql())
 
-	sqlStr.WriteString(" for update")
-
-	db.trace(sqlStr.String())
+	sqlStr.WriteString(db.getDialect().ForUpdate())
 
-	rows, err := db.tx.Query(sqlStr.String(), db.params...)
+	rows, err := db.queryContext(sqlStr.String(), db.params...)
 
 	if err != nil {
 
@@ -1270,119 +1489,58 @@ func (db *ConDB) GetForUpdate(out interface{}) error {
 	}
 	defer rows.Close()
 
-	//return rowsToStruct(rows, out)
-	mp, err := rowsToMap(rows)
-	StructOfMap(out, mp)
-	return err
+	if err := rowsToStruct(rows, out); err != nil {
+		return err
+	}
+	return db.afterFind(out)
 
 }
 
-func (m *ConDB) QueryRow(query string, args ...interface{}) *sql.Row {
-	m.trace(query, args...)
-	return m.Db.QueryRow(query, args...)
+func (m *ConDB) QueryRow(query string, args ...interface{}) *Row {
+
+	return m.queryRowContext(query, args...)
 }
 
 func (m *ConDB) QueryRows(query string, args ...interface{}) (*sql.Rows, error) {
-	m.trace(query, args...)
-	return m.Db.Query(query, args...)
+	return m.queryContext(query, args...)
 }
 
 func (m *ConDB) QueryMap(query string, args ...interface{}) (map[string]string, error) {
 
-	sqlstr := conver(0, query)
-	m.trace(sqlstr, args...)
-	rows, err := m.Db.Query(sqlstr, args...)
+	sqlstr := conver(m.getDialect(), 0, query)
+	rows, err := m.queryContext(sqlstr, args...)
 	if err != nil {
 
 		return nil, err
 	}
 	defer rows.Close()
-	return rowsToMap(rows)
+	return rowsToMap(rows, false)
 }
 
 func (m *ConDB) QueryMaps(query string, args ...interface{}) ([]map[string]string, error) {
 
-	sqlstr := conver(0, query)
-	m.trace(sqlstr, args...)
-	rows, err := m.Db.Query(sqlstr, args...)
+	sqlstr := conver(m.getDialect(), 0, query)
+	rows, err := m.queryContext(sqlstr, args...)
 	if err != nil {
 
 		return nil, err
 	}
 	defer rows.Close()
-	return rowsToMaps(rows)
-}
-
-type emptyInterface struct {
-	typ  *struct{}
-	word unsafe.Pointer
+	return rowsToMaps(rows, false)
 }
 
 func StructOfMap(struct_ interface{}, data map[string]string) {
 
-	structInter := (interface{})(struct_)
-
-	t := reflect.TypeOf(structInter).Elem()
-
-	structPtr := (*emptyInterface)(unsafe.Pointer(&structInter)).word
-
-	for i, m := 0, t.NumField(); i < m; i++ {
-
-		obj := t.Field(i)
-
-		col := obj.Tag.Get("db")
-		val := data[col]
-		key := obj.Name
-
-		if obj.Anonymous { // 输出匿名字段结构
-
-			for x, mm := 0, obj.Type.NumField(); x < mm; x++ {
-
-				fld := obj.Type.Field(x)
-				tag := fld.Tag.Get("db")
-				kind := fld.Type.Name()
-
-				val = data[tag]
-
-				field, _ := t.FieldByName(fld.Name)
-				fieldPtr := uintptr(structPtr) + field.Offset
-
-				if kind == "string" {
-					*((*string)(unsafe.Pointer(fieldPtr))) = val
-				} else if kind == "int32" {
-					*((*int32)(unsafe.Pointer(fieldPtr))) = Int32(val)
-				} else if kind == "int64" {
-					*((*int64)(unsafe.Pointer(fieldPtr))) = Int64(val)
-				}
-			}
-		} else {
-
-			field, _ := t.FieldByName(key)
-			fieldPtr := uintptr(structPtr) + field.Offset
-
-			kind := obj.Type.Name()
-
-			if kind == "string" {
-				*((*string)(unsafe.Pointer(fieldPtr))) = val
-			} else if kind == "int32" {
-				*((*int32)(unsafe.Pointer(fieldPtr))) = Int32(val)
-			} else if kind == "int64" {
-				*((*int64)(unsafe.Pointer(fieldPtr))) = Int64(val)
-			} else if kind == "float64" {
-				*((*float64)(unsafe.Pointer(fieldPtr))) = Float64(val)
-			} else {
-				*((*string)(unsafe.Pointer(fieldPtr))) = val
-			}
-		}
-	}
-
+	mapToStruct(data, struct_)
 }
 
 func argsToStr(args ...interface{}) string {
 	var margs string
 	for i, a := range args {
 		var v interface{} = a
-		if x, ok := v.(driver.Valuer); ok {
+		if _, ok := v.(sensitiveValue); ok {
+			//敏感参数按 %v 打印为 ***，不取原值
+		} else if x, ok := v.(driver.Valuer); ok {
 			y, err := x.Value()
 			if err == nil {
 				v = y
@@ -1407,68 +1565,56 @@ func SliceClear(s *[]interface{}) {
 
 func (db *ConDB) buildSql() string {
 
-	sql := bytes.Buffer{}
 	SliceClear(&db.params)
-	if len(db.Condition) > 0 {
-
-		sql.WriteString(" WHERE ")
-
-		i := 0
-		for _, clause := range db.Condition {
+	db.params = append(db.params, db.joinParams...)
+	db.joinParams = db.joinParams[:0]
 
-			query := clause["query"].(string)
-			values := clause["args"].([]interface{})
-			if i > 0 {
-				sql.WriteString(" AND ")
-			}
+	//条件按添加顺序组合，Or 与之前的全部条件以 OR 连接，之后的 Where 再与整体以 AND 连接，
+	//如 Where(a).Where(b).Or(c).Where(d) 生成 ((a AND b) OR c) AND d
+	where, op := "", ""
+	add := func(query string, values []interface{}, or bool) {
 
-			query = db.Conver(query)
-			sql.WriteString(query)
+		clauseOp := "AND"
+		if or {
+			clauseOp = "OR"
+		}
+		query, values = expandSubqueries(query, values)
+		if topLevel(query, "OR") {
+			query = "(" + query + ")"
+		}
+		query = db.Conver(query)
+		db.params = append(db.params, values...)
 
-			for _, vv := range values {
+		if where == "" {
 
-				db.params = append(db.params, vv)
-			}
-			i++
+			where = query
+			return
 		}
-
+		if op != "" && op != clauseOp {
+			where = "(" + where + ")"
+		}
+		where = where + " " + clauseOp + " " + query
+		op = clauseOp
 	}
-	if len(db.OrCondition) > 0 {
-
-		sql.WriteString(" OR ")
-
-		i := 0
 
-		for _, clause := range db.OrCondition {
-
-			query := clause["query"].(string)
-			values := clause["args"].([]interface{})
-			if i > 0 {
-				sql.WriteString(" OR ")
-			}
-			query = db.Conver(query)
-			sql.WriteString(query)
-
-			for _, vv := range values {
-
-				db.params = append(db.params, vv)
-			}
-			i++
-		}
+	for _, clause := range db.Condition {
 
+		or, _ := clause["or"].(bool)
+		add(clause["query"].(string), clause["args"].([]interface{}), or)
+	}
+	for _, clause := range db.OrCondition {
+		add(clause["query"].(string), clause["args"].([]interface{}), true)
 	}
 	if db.inCondition != "" {
+		add(db.inCondition, nil, false)
+	}
 
-		if len(db.Condition) > 0 {
-
-			sql.WriteString(" AND ")
-		} else {
-			sql.WriteString("  ")
-		}
-		sql.WriteString(db.inCondition)
-
+	//占位符按语句编号，下一条语句重新从 1 开始
+	db.Idx = 0
+	if where == "" {
+		return ""
 	}
-	return sql.String()
+	return " WHERE " + where
 }
 
 func (db *ConDB) createSql() string {
@@ -1506,11 +1652,7 @@ func (db *ConDB) createSql() string {
 func (db *ConDB) Conver(str string) string {
 
 	sum := strings.Count(str, "?")
-	for i := 1; i <= sum; i++ {
-
-		str = strings.Replace(str, "?", fmt.Sprintf(":%d", i+db.Idx), 1)
-
-	}
+	str = conver(db.getDialect(), db.Idx, str)
 	db.Idx += sum
 	return str
 }
@@ -1585,7 +1727,9 @@ func buildSelectQuery(clause map[string]interface{}) (str string) {
 	return
 }
 
-func insertSql(db *ConDB, i interface{}) string {
+// insertSql 返回插入语句的字段和占位符部分及对应的参数，auto 表示主键由序列或数据库生成，
+// 取序列值失败时返回错误
+func insertSql(db *ConDB, i interface{}) (string, []interface{}, bool, error) {
 
 	val := reflect.ValueOf(i)
 	getType := reflect.TypeOf(i)
@@ -1599,58 +1743,84 @@ func insertSql(db *ConDB, i interface{}) string {
 				mv.Call(nil)
 			}
 		}*/
-	_, ins := getType.MethodByName("PreInsert")
-	if ins {
-		mv := val.MethodByName("PreInsert")
-		mv.Call(nil)
-	}
 	data := toMap(val, getType)
+	d := db.getDialect()
+
+	idKey := getModel(getType).keyColumn()
+	id, ok := data[idKey]
+	autoSeq := !ok || isZero(bindValue(id))
+	if autoSeq {
+
+		//主键由触发器或自增列生成，通过 Returning 带回
+		if seqSql := d.Sequence(db.table); seqSql != "" && !contains(db.returning, idKey) {
+
+			var seq int64
+
+			err := db.queryRowContext(seqSql).Scan(&seq)
+			if err != nil {
+
+				db.trace("seq error:", err)
+				return "", nil, autoSeq, err
+			}
+			db.LastInsertId = seq
+			data[idKey] = seq
+		} else {
+			delete(data, idKey)
+		}
+	}
+
 	buff := bytes.NewBuffer([]byte{})
 	value := bytes.NewBuffer([]byte{})
+	args := make([]interface{}, 0, len(data))
 
-	autoSeq := true
 	for k, v := range data {
 
-		if k == "ID" {
-
-			id := parseString(v)
-			if id == "" || id == "0" {
-				continue
-			} else {
-				autoSeq = false
-			}
+		v = bindValue(v)
+		if isZero(v) && contains(db.returning, k) { //由数据库默认值或触发器填充
+			continue
 		}
+		args = append(args, v)
+
 		buff.WriteString(",")
 		buff.WriteString(k)
 
-		value.WriteString(",'")
-		value.WriteString(parseString(v))
-		value.WriteString("'")
-
+		value.WriteString(",")
+		value.WriteString(d.Placeholder(len(args)))
 	}
 
 	key := strings.TrimLeft(buff.String(), `,`)
 	vas := strings.TrimLeft(value.String(), `,`)
 
-	var sql string
-	if autoSeq {
+	return ` (` + key + `) values (` + vas + `)`, args, autoSeq, nil
+}
 
-		var seq int64
+// bindValue 返回字段对应的绑定参数，已注册转换器的类型由转换器生成，
+// nil 指针绑定为 NULL，其余指针取其指向的值
+func bindValue(v interface{}) interface{} {
 
-		err := db.Db.QueryRow("select seq_" + db.table + ".nextval from dual").Scan(&seq)
-		if err != nil {
+	if c := getConverter(reflect.TypeOf(v)); c != nil {
+		return converterValue{c, v}
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
 
-			db.trace("seq error:", err)
-			return ""
+		if rv.IsNil() {
+			return nil
 		}
-		db.LastInsertId = seq
-
-		sql = fmt.Sprintf(` (ID,%s ) values (%d,%s)`, key, seq, vas)
-	} else {
-		sql = ` (` + key + `) values (` + vas + `)`
+		rv = rv.Elem()
 	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+func isZero(v interface{}) bool {
 
-	return sql
+	return v == nil || reflect.ValueOf(v).IsZero()
 }
 
 func parseString(value interface{}, args ...int) (s string) {
@@ -1693,71 +1863,32 @@ func parseString(value interface{}, args ...int) (s string) {
 
 func toMap(v reflect.Value, t reflect.Type) map[string]interface{} {
 
-	m := make(map[string]interface{})
-	vt := t.Elem()
-	vv := v.Elem()
+	model := getModel(t)
+	vv := reflect.Indirect(v)
 
-	for i := 0; i < vt.NumField(); i++ {
+	m := make(map[string]interface{}, len(model.fields))
+	for _, f := range model.fields {
 
-		key := vt.Field(i)
-		tag := key.Tag.Get("db")
-
-		obj := vt.Field(i)
-		value := vv.Field(i).Interface()
-
-		if obj.Anonymous { // 输出匿名字段结构
-
-			for x := 0; x < obj.Type.NumField(); x++ {
-
-				af := obj.Type.Field(x)
-				tag := af.Tag.Get("db")
-				vv := reflect.ValueOf(value)
-				vl := vv.Field(x).Interface()
-				m[tag] = vl
-			}
-		} else {
-			m[tag] = value
+		if f.readOnly {
+			continue
 		}
+		m[f.column] = f.bind(vv)
 	}
 	return m
 }
 
 func structToMap(i interface{}) map[string]interface{} {
 
-	m := make(map[string]interface{})
-	vt := reflect.TypeOf(i).Elem()
+	model := getModel(reflect.TypeOf(i))
 	vv := reflect.ValueOf(i).Elem()
 
-	for i, mn := 0, vt.NumField(); i < mn; i++ {
+	m := make(map[string]interface{}, len(model.fields))
+	for _, f := range model.fields {
 
-		key := vt.Field(i)
-		tag := key.Tag.Get("db")
-		mk := key.Tag.Get("key")
-
-		value := vv.Field(i).Interface()
-
-		if key.Anonymous { // 输出匿名字段结构
-
-			for x, mm := 0, key.Type.NumField(); x < mm; x++ {
-
-				field := key.Type.Field(x)
-
-				tag := field.Tag.Get("db")
-
-				vv := reflect.ValueOf(value)
-
-				vl := vv.Field(x).Interface()
-
-				m[tag] = vl
-			}
-		} else if mk == "auto" {
+		if f.auto || f.readOnly {
 			continue
-		} else {
-
-			//fmt.Printf("%q => %q, ", chKey, vv.FieldByName(key.Name).String())
-			//fmt.Printf("第%d个字段是：%s:%v = %v \n", i+1, key.Name, key.Type, value)
-			m[tag] = value
 		}
+		m[f.column] = f.bind(vv)
 	}
 	return m
 }
@@ -1765,91 +1896,27 @@ func structToMap(i interface{}) map[string]interface{} {
 func mapToStruct(data map[string]string, c interface{}) {
 
 	pv := reflect.ValueOf(c).Elem()
-	pt := reflect.TypeOf(c).Elem()
-
-	for i, mn := 0, pt.NumField(); i < mn; i++ {
-
-		obj := pt.Field(i)
-
-		key := pt.Field(i).Name
-		ktype := pt.Field(i).Type
-		col := pt.Field(i).Tag.Get("db")
-		value := data[col]
-
-		val := reflect.ValueOf(value)
-		vtype := reflect.TypeOf(value)
+	model := getModel(pv.Type())
 
-		if ktype != vtype {
+	for col, value := range data {
 
-			val, _ = conversionType(value, ktype.Name())
-		}
-
-		if obj.Anonymous { // 输出匿名字段结构
-
-			for x, max := 0, obj.Type.NumField(); x < max; x++ {
-
-				af := obj.Type.Field(x)
-
-				k := af.Name
-				t := af.Type
-				d := af.Tag.Get("db")
-
-				vl := data[d]
-
-				av := reflect.ValueOf(vl)
-				at := reflect.TypeOf(vl)
-
-				if t != at {
-					av, _ = conversionType(vl, t.Name())
-				}
-				pv.FieldByName(k).Set(av)
-
-			}
-		} else {
-
-			pv.FieldByName(key).Set(val)
+		if f := model.column(col); f != nil {
+			setValue(f.value(pv), value)
 		}
 	}
 }
-func conversionType(value string, ktype string) (reflect.Value, error) {
-
-	if ktype == "string" {
-
-		return reflect.ValueOf(ktype), nil
-	} else if ktype == "int64" {
 
-		buf, err := strconv.ParseInt(value, 10, 64)
-		return reflect.ValueOf(buf), err
-	} else if ktype == "int32" {
+// setValue 把字符串形式的列值转换为字段类型后写入，类型不支持时忽略
+func setValue(field reflect.Value, value string) {
 
-		buf, err := strconv.ParseInt(value, 10, 64)
-		return reflect.ValueOf(int32(buf)), err
-	} else if ktype == "int8" {
+	if field.Kind() == reflect.String {
 
-		buf, err := strconv.ParseInt(value, 10, 64)
-		return reflect.ValueOf(int8(buf)), err
-	} else if ktype == "int" {
-
-		buf, err := strconv.Atoi(value)
-		return reflect.ValueOf(buf), err
-	} else if ktype == "float32" {
-
-		buf, err := strconv.ParseFloat(value, 64)
-		return reflect.ValueOf(float32(buf)), err
-	} else if ktype == "float64" {
-
-		buf, err := strconv.ParseFloat(value, 64)
-		return reflect.ValueOf(buf), err
-	} else if ktype == "time.Time" {
-
-		buf, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
-		return reflect.ValueOf(buf), err
-	} else if ktype == "Time" {
+		field.SetString(value)
+		return
+	}
 
-		buf, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
-		return reflect.ValueOf(buf), err
-	} else {
-		return reflect.ValueOf(ktype), nil
+	if vl, err := conversionType(value, field.Type()); err == nil {
+		field.Set(vl)
 	}
 }
 
@@ -1865,55 +1932,13 @@ func mapReflect(m map[string]string, v reflect.Value) error {
 	kind := typ.Kind()
 	//fmt.Println("type:", kind)
 	if reflect.Struct == kind {
-		for i := 0; i < val.NumField(); i++ {
-
-			obj := typ.Field(i)
-
-			if obj.Anonymous { // 输出匿名字段结构
 
-				value := val.Field(i)
-				for x := 0; x < obj.Type.NumField(); x++ {
+		model := getModel(typ)
+		for col, meta := range m {
 
-					af := obj.Type.Field(x)
-
-					key := af.Name
-					ktype := af.Type
-					tag := af.Tag.Get("db")
-
-					meta := m[tag]
-
-					vl := reflect.ValueOf(meta)
-					vt := reflect.TypeOf(meta)
-
-					if ktype != vt {
-						vl, _ = conversionType(meta, ktype.Name())
-					}
-					value.FieldByName(key).Set(vl)
-
-				}
+			if f := model.column(col); f != nil {
+				setValue(f.value(val), meta)
 			}
-
-			key := obj.Name
-			ktype := obj.Type
-
-			col := obj.Tag.Get("db")
-			if len(col) == 0 {
-				continue
-			}
-			meta, ok := m[col]
-			if !ok {
-				continue
-			}
-
-			vl := reflect.ValueOf(meta)
-			vt := reflect.TypeOf(meta)
-
-			if ktype != vt {
-				vl, _ = conversionType(meta, ktype.Name())
-			}
-
-			val.FieldByName(key).Set(vl)
-
 		}
 	} else if kind == reflect.Int64 || kind == reflect.Int32 || kind == reflect.Int {
 
@@ -1930,11 +1955,11 @@ func mapReflect(m map[string]string, v reflect.Value) error {
 
 		for _, value := range m {
 
-			integer64, err := strconv.ParseInt(value, 10, 64)
+			f, err := strconv.ParseFloat(value, 64)
 			if err != nil {
 				return err
 			}
-			val.SetInt(integer64)
+			val.SetFloat(f)
 		}
 
 	} else if kind == reflect.Bool {
@@ -2032,76 +2057,77 @@ func mapReflect(m map[string]string, v reflect.Value) error {
 
 func rowsToList(rows *sql.Rows, in interface{}) error {
 
-	d, err := rowsToMaps(rows)
-	if err != nil {
-		return err
-	}
+	v := reflect.ValueOf(in).Elem()
+	elem := v.Type().Elem()
 
-	length := len(d)
+	base := elem
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
 
-	if length > 0 {
-		v := reflect.ValueOf(in).Elem()
+	var s *scanner
+	if isStruct(base) {
 
-		newv := reflect.MakeSlice(v.Type(), 0, length)
-		v.Set(newv)
-		v.SetLen(length)
+		var err error
+		if s, err = newScanner(rows, base); err != nil {
+			return err
+		}
+	}
 
-		index := 0
-		for i := 0; i < length; i++ {
+	list := reflect.MakeSlice(v.Type(), 0, 0)
+	for rows.Next() {
 
-			k := v.Type().Elem()
+		obj := reflect.New(base).Elem()
 
-			newObj := reflect.New(k)
-			err := mapReflect(d[i], newObj)
-			if err != nil {
-				return err
-			}
+		var err error
+		if s != nil {
+			err = s.scan(rows, obj)
+		} else {
+			err = scanValue(rows, obj)
+		}
+		if err != nil {
+			return err
+		}
 
-			v.Index(index).Set(newObj.Elem())
-			index++
+		if elem.Kind() == reflect.Ptr {
+			obj = obj.Addr()
 		}
-		v.SetLen(index)
+		list = reflect.Append(list, obj)
 	}
-	return nil
-}
-func rowsToStruct(rows *sql.Rows, out interface{}) error {
-
-	column, err := rows.Columns() //读出查询出的列字段名
-	if err != nil {
-		//logger.Error(err)
+	if err := rows.Err(); err != nil {
 		return err
 	}
 
-	values := make([][]byte, len(column))     //values是每个列的值，这里获取到byte里
-	scans := make([]interface{}, len(column)) //因为每次查询出来的列是不定长的，用len(column)定住当次查询的长度
-
-	for i := range values {
-
-		scans[i] = &values[i]
+	if list.Len() > 0 {
+		v.Set(list)
 	}
+	return nil
+}
+func rowsToStruct(rows *sql.Rows, out interface{}) error {
 
-	for rows.Next() {
+	v := reflect.ValueOf(out).Elem()
 
-		if err := rows.Scan(scans...); err != nil {
-			//query.Scan查询出来的不定长值放到scans[i] = &values[i],也就是每行都放在values里
-			return err
-		}
+	if !rows.Next() {
 
-		row := make(map[string]string) //每行数据
-		for k, v := range values {
-			//每行数据是放在values里面，现在把它挪到row里
-			key := column[k]
-			row[key] = string(v)
+		if err := rows.Err(); err != nil {
+			return err
 		}
+		return ErrNotFound
+	}
 
-		mapToStruct(row, out)
-		return nil
+	if !isStruct(v.Type()) {
+		return scanValue(rows, v)
 	}
 
-	return errors.New("not found rows")
+	s, err := newScanner(rows, v.Type())
+	if err != nil {
+		return err
+	}
+	return s.scan(rows, v)
 }
 
-func rowsToMap(rows *sql.Rows) (map[string]string, error) {
+// rowsToMap 读取第一行，joined 为 true 时同名的列保留第一个，否则与原来一样保留最后一个
+func rowsToMap(rows *sql.Rows, joined bool) (map[string]string, error) {
 
 	column, err := rows.Columns() //读出查询出的列字段名
 	if err != nil {
@@ -2129,6 +2155,9 @@ func rowsToMap(rows *sql.Rows) (map[string]string, error) {
 		for k, v := range values {
 			//每行数据是放在values里面，现在把它挪到row里
 			key := column[k]
+			if _, ok := row[key]; ok && joined { //连接查询中同名的列保留第一个，即主表的列
+				continue
+			}
 			row[key] = string(v)
 		}
 		return row, nil
@@ -2137,7 +2166,8 @@ func rowsToMap(rows *sql.Rows) (map[string]string, error) {
 	return nil, errors.New("not found rows")
 }
 
-func rowsToMaps(rows *sql.Rows) ([]map[string]string, error) {
+// rowsToMaps 读取全部行，joined 的含义同 rowsToMap
+func rowsToMaps(rows *sql.Rows, joined bool) ([]map[string]string, error) {
 
 	column, err := rows.Columns() //读出查询出的列字段名
 	if err != nil {
@@ -2166,6 +2196,9 @@ func rowsToMaps(rows *sql.Rows) ([]map[string]string, error) {
 		for k, v := range values {
 			//每行数据是放在values里面，现在把它挪到row里
 			key := column[k]
+			if _, ok := row[key]; ok && joined { //连接查询中同名的列保留第一个，即主表的列
+				continue
+			}
 			row[key] = string(v)
 		}
 		results = append(results, row)
@@ -2174,15 +2207,24 @@ func rowsToMaps(rows *sql.Rows) ([]map[string]string, error) {
 	return results, nil
 }
 
-func conver(idx int, str string) string {
+func conver(d Dialect, idx int, str string) string {
 
-	sum := strings.Count(str, "?")
-	for i := 1; i <= sum; i++ {
+	if !strings.Contains(str, "?") {
+		return str
+	}
 
-		str = strings.Replace(str, "?", fmt.Sprintf(":%d", i+idx), 1)
+	//一次遍历替换，In 展开的长参数列表不会反复复制字符串
+	buff := strings.Builder{}
+	for _, c := range str {
 
+		if c == '?' {
+			idx++
+			buff.WriteString(d.Placeholder(idx))
+			continue
+		}
+		buff.WriteRune(c)
 	}
-	return str
+	return buff.String()
 }
 
 func Int(f string) int {
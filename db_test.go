@@ -0,0 +1,159 @@
+package oram
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+type ctxPerson struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestWithContext(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		run  func(db *ConDB) error
+	}{
+		{"Count", func(db *ConDB) error {
+			q := db.Table("tb_person").Where("id=?", 1)
+			q.Count()
+			return q.Err
+		}},
+		{"Find", func(db *ConDB) error {
+			var list []ctxPerson
+			return db.Where("id=?", 1).Find(&list).Err
+		}},
+		{"Get", func(db *ConDB) error {
+			var p ctxPerson
+			return db.Where("id=?", 1).Get(&p)
+		}},
+		{"Insert", func(db *ConDB) error {
+			return db.Insert(&ctxPerson{Name: "a"})
+		}},
+		{"Update", func(db *ConDB) error {
+			return db.Table("tb_person").Where("id=?", 1).Update("name=?", "b")
+		}},
+		{"Delete", func(db *ConDB) error {
+			return db.Table("tb_person").Where("id=?", 1).Delete()
+		}},
+		{"TxBegin", func(db *ConDB) error {
+			return db.TxBegin().Err
+		}},
+	}
+	for _, tt := range tests {
+
+		if err := tt.run(db.WithContext(ctx)); err != context.Canceled {
+			t.Errorf("%s with canceled context: err = %v, want %v", tt.name, err, context.Canceled)
+		}
+	}
+	if s := d.statements(); len(s) != 0 {
+		t.Errorf("canceled statements reached the driver: %q", s)
+	}
+
+	//WithContext 不改变初始化的 ConDB
+	if err := db.Table("tb_person").Where("id=?", 1).Delete(); err != nil {
+		t.Errorf("Delete without context: %v", err)
+	}
+	if db.Context() != context.Background() {
+		t.Errorf("root ConDB bound to %v", db.Context())
+	}
+}
+
+func TestInsertParams(t *testing.T) {
+
+	db, d := newFakeDB(Oracle)
+
+	//值全部绑定为参数，不拼接到语句中
+	p := ctxPerson{Name: "O'Brien"}
+	if err := db.Table("tb_person").Insert(&p); err != nil {
+		t.Fatal(err)
+	}
+	s := d.statements()
+	if len(s) != 2 || !strings.HasSuffix(s[1], "[O'Brien 1]") && !strings.HasSuffix(s[1], "[1 O'Brien]") {
+		t.Errorf("statements = %q", s)
+	}
+	if p.Id != 1 {
+		t.Errorf("Id = %d, want the sequence value 1", p.Id)
+	}
+
+	//取序列值失败时不执行插入
+	db, d = newFakeDB(Oracle)
+	d.failOn = "nextval"
+	if err := db.Table("tb_person").Insert(&ctxPerson{Name: "a"}); err == nil {
+		t.Error("Insert succeeded without a sequence value")
+	}
+	if s := d.statements(); len(s) != 1 {
+		t.Errorf("statements = %q, want only the sequence query", s)
+	}
+}
+
+type returningPerson struct {
+	Id      int64  `db:"id"`
+	Name    string `db:"name"`
+	Created string `db:"created"`
+}
+
+func TestReturning(t *testing.T) {
+
+	row := func() []*fakeRows {
+		return []*fakeRows{{cols: []string{"id", "created"}, data: [][]driver.Value{{int64(12), "2024-01-02"}}}}
+	}
+
+	tests := []struct {
+		dialect Dialect
+		want    string
+		err     bool
+	}{
+		{Oracle, "INSERT INTO  tb_person (name) values (:1) RETURNING id,created INTO :2,:3", false},
+		{PostgreSQL, "INSERT INTO  tb_person (name) values ($1) RETURNING id,created [a]", false},
+		{MySQL, "", true},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(tt.dialect)
+		d.results = row()
+
+		p := returningPerson{Name: "a"}
+		err := db.Table("tb_person").Returning("id", "created").Insert(&p)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: err = %v", tt.dialect.Name(), err)
+		}
+
+		s := d.statements()
+		if tt.err {
+			if len(s) != 0 {
+				t.Errorf("%s: statements = %q, want none", tt.dialect.Name(), s)
+			}
+			continue
+		}
+		if len(s) != 1 || !strings.HasPrefix(s[0], tt.want) {
+			t.Errorf("%s: statements = %q, want %q", tt.dialect.Name(), s, tt.want)
+		}
+		if p.Id != 12 || p.Created != "2024-01-02" {
+			t.Errorf("%s: got %+v, want returned id and created", tt.dialect.Name(), p)
+		}
+	}
+
+	//Update 写回 Model 传入的结构体
+	db, d := newFakeDB(PostgreSQL)
+	d.results = []*fakeRows{{cols: []string{"created"}, data: [][]driver.Value{{"2024-02-03"}}}}
+	p := returningPerson{Id: 12}
+	if err := db.Model(&p).Where("id=?", 12).Returning("created").Update("name=?", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.statements(); len(s) != 1 || s[0] != "UPDATE tb_returningperson set name=$1 WHERE id=$2 RETURNING created [b 12]" {
+		t.Errorf("statements = %q", s)
+	}
+	if p.Created != "2024-02-03" {
+		t.Errorf("Created = %q, want the returned value", p.Created)
+	}
+}
@@ -0,0 +1,337 @@
+package oram
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect 封装不同数据库的 SQL 语法差异，默认使用 Oracle
+type Dialect interface {
+	// Name 返回数据库名称，如 oracle、mysql
+	Name() string
+	// Placeholder 返回第 i 个（从 1 开始）绑定参数的占位符
+	Placeholder(i int) string
+	// Paginate 为查询语句加上分页，跳过 offset 行，最多返回 limit 行
+	Paginate(query string, offset, limit int32) string
+	// LimitOne 限制查询语句只返回一行
+	LimitOne(query string) string
+	// Sequence 返回获取 table 下一个主键值的查询语句，主键由数据库在插入时生成则返回空
+	Sequence(table string) string
+	// Sequences 返回一次获取 table 后续 n 个主键值的查询语句，主键由数据库在插入时生成则返回空
+	Sequences(table string, n int) string
+	// Returning 返回插入语句获取自增主键 key 的子句，使用 LastInsertId 则返回空
+	Returning(key string) string
+	// ReturningInto 返回写语句带回 columns 的子句，out 为 true 时以输出参数绑定，
+	// 编号从 start+1 开始，否则以结果行返回；不支持时返回空
+	ReturningInto(columns []string, start int) (clause string, out bool)
+	// BatchInsert 返回一次插入 rows 行的语句，占位符按行依次编号
+	BatchInsert(table string, columns []string, rows int) string
+	// FirstInsertId 根据批量插入后的 LastInsertId 计算第一行的主键
+	FirstInsertId(lastInsertId int64, rows int) int64
+	// MaxBindVars 返回一条语句最多绑定的参数个数
+	MaxBindVars() int
+	// Upsert 返回按 conflict 字段插入或更新的语句，columns 按顺序绑定，匹配时更新 update 字段；
+	// seqKey 非空时为由序列生成的主键列，只在插入时取序列值
+	Upsert(table, seqKey string, columns, conflict, update []string) string
+	// ForUpdate 返回行锁子句，不支持时返回空
+	ForUpdate() string
+}
+
+var (
+	Oracle     Dialect = oracleDialect{}
+	MySQL      Dialect = mysqlDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+	SQLite     Dialect = sqliteDialect{}
+)
+
+type oracleDialect struct{}
+
+func (oracleDialect) Name() string { return "oracle" }
+
+func (oracleDialect) Placeholder(i int) string { return ":" + strconv.Itoa(i) }
+
+func (oracleDialect) Paginate(query string, offset, limit int32) string {
+
+	return fmt.Sprintf("SELECT * FROM (SELECT TT.*, ROWNUM AS ROWNO FROM (%s) TT  WHERE ROWNUM <= %d) TABLE_ALIAS WHERE TABLE_ALIAS.ROWNO > %d", query, offset+limit, offset)
+}
+
+func (oracleDialect) LimitOne(query string) string {
+
+	return fmt.Sprintf("SELECT * FROM (%s) WHERE ROWNUM = 1", query)
+}
+
+func (oracleDialect) Sequence(table string) string {
+
+	return "select seq_" + table + ".nextval from dual"
+}
+
+func (oracleDialect) Sequences(table string, n int) string {
+
+	return fmt.Sprintf("select seq_%s.nextval from dual connect by level <= %d", table, n)
+}
+
+func (oracleDialect) Returning(key string) string { return "" }
+
+func (d oracleDialect) ReturningInto(columns []string, start int) (string, bool) {
+
+	return " RETURNING " + strings.Join(columns, ",") + " INTO " + placeholders(d, start, len(columns)), true
+}
+
+func (d oracleDialect) BatchInsert(table string, columns []string, rows int) string {
+
+	buff := bytes.NewBufferString("INSERT ALL")
+	cols := strings.Join(columns, ",")
+	for i := 0; i < rows; i++ {
+
+		buff.WriteString(" INTO ")
+		buff.WriteString(table)
+		buff.WriteString(" (")
+		buff.WriteString(cols)
+		buff.WriteString(") VALUES (")
+		buff.WriteString(placeholders(d, i*len(columns), len(columns)))
+		buff.WriteString(")")
+	}
+	buff.WriteString(" SELECT 1 FROM DUAL")
+	return buff.String()
+}
+
+func (oracleDialect) FirstInsertId(lastInsertId int64, rows int) int64 { return lastInsertId }
+
+func (oracleDialect) MaxBindVars() int { return 65535 }
+
+func (d oracleDialect) Upsert(table, seqKey string, columns, conflict, update []string) string {
+
+	buff := bytes.NewBufferString("MERGE INTO ")
+	buff.WriteString(table)
+	buff.WriteString(" T USING (SELECT ")
+	for i, col := range columns {
+
+		if i > 0 {
+			buff.WriteString(",")
+		}
+		buff.WriteString(d.Placeholder(i + 1))
+		buff.WriteString(" AS ")
+		buff.WriteString(col)
+	}
+	buff.WriteString(" FROM dual) S ON (")
+	for i, col := range conflict {
+
+		if i > 0 {
+			buff.WriteString(" AND ")
+		}
+		buff.WriteString("T." + col + "=S." + col)
+	}
+	buff.WriteString(")")
+
+	if len(update) > 0 {
+
+		buff.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for i, col := range update {
+
+			if i > 0 {
+				buff.WriteString(",")
+			}
+			buff.WriteString("T." + col + "=S." + col)
+		}
+	}
+
+	buff.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	buff.WriteString(strings.Join(columns, ","))
+	if seqKey != "" {
+		buff.WriteString("," + seqKey)
+	}
+	buff.WriteString(") VALUES (S.")
+	buff.WriteString(strings.Join(columns, ",S."))
+	if seqKey != "" {
+		buff.WriteString(",seq_" + table + ".nextval")
+	}
+	buff.WriteString(")")
+	return buff.String()
+}
+
+func (oracleDialect) ForUpdate() string { return " FOR UPDATE" }
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Placeholder(i int) string { return "?" }
+
+func (mysqlDialect) Paginate(query string, offset, limit int32) string {
+
+	return fmt.Sprintf("%s LIMIT %d,%d", query, offset, limit)
+}
+
+func (mysqlDialect) LimitOne(query string) string { return query + " LIMIT 1" }
+
+func (mysqlDialect) Sequence(table string) string { return "" }
+
+func (mysqlDialect) Sequences(table string, n int) string { return "" }
+
+func (mysqlDialect) Returning(key string) string { return "" }
+
+func (mysqlDialect) ReturningInto(columns []string, start int) (string, bool) { return "", false }
+
+func (d mysqlDialect) BatchInsert(table string, columns []string, rows int) string {
+
+	return valuesInsert(d, table, columns, rows)
+}
+
+// mysql 批量插入时 LastInsertId 返回第一行的主键
+func (mysqlDialect) FirstInsertId(lastInsertId int64, rows int) int64 { return lastInsertId }
+
+func (mysqlDialect) MaxBindVars() int { return 65535 }
+
+func (d mysqlDialect) Upsert(table, seqKey string, columns, conflict, update []string) string {
+
+	sql := valuesInsert(d, table, columns, 1)
+	if len(update) == 0 {
+		return strings.Replace(sql, "INSERT INTO", "INSERT IGNORE INTO", 1)
+	}
+
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = col + "=VALUES(" + col + ")"
+	}
+	return sql + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ",")
+}
+
+func (mysqlDialect) ForUpdate() string { return " FOR UPDATE" }
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Placeholder(i int) string { return "$" + strconv.Itoa(i) }
+
+func (postgresDialect) Paginate(query string, offset, limit int32) string {
+
+	return fmt.Sprintf("%s LIMIT %d OFFSET %d", query, limit, offset)
+}
+
+func (postgresDialect) LimitOne(query string) string { return query + " LIMIT 1" }
+
+func (postgresDialect) Sequence(table string) string { return "" }
+
+func (postgresDialect) Sequences(table string, n int) string { return "" }
+
+func (postgresDialect) Returning(key string) string { return " RETURNING " + key }
+
+func (postgresDialect) ReturningInto(columns []string, start int) (string, bool) {
+
+	return " RETURNING " + strings.Join(columns, ","), false
+}
+
+func (d postgresDialect) BatchInsert(table string, columns []string, rows int) string {
+
+	return valuesInsert(d, table, columns, rows)
+}
+
+func (postgresDialect) FirstInsertId(lastInsertId int64, rows int) int64 { return lastInsertId }
+
+func (postgresDialect) MaxBindVars() int { return 65535 }
+
+func (d postgresDialect) Upsert(table, seqKey string, columns, conflict, update []string) string {
+
+	return onConflict(valuesInsert(d, table, columns, 1), conflict, update)
+}
+
+func (postgresDialect) ForUpdate() string { return " FOR UPDATE" }
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Placeholder(i int) string { return "?" }
+
+func (sqliteDialect) Paginate(query string, offset, limit int32) string {
+
+	return fmt.Sprintf("%s LIMIT %d OFFSET %d", query, limit, offset)
+}
+
+func (sqliteDialect) LimitOne(query string) string { return query + " LIMIT 1" }
+
+func (sqliteDialect) Sequence(table string) string { return "" }
+
+func (sqliteDialect) Sequences(table string, n int) string { return "" }
+
+func (sqliteDialect) Returning(key string) string { return "" }
+
+// sqlite 3.35 起支持 RETURNING
+func (sqliteDialect) ReturningInto(columns []string, start int) (string, bool) {
+
+	return " RETURNING " + strings.Join(columns, ","), false
+}
+
+func (d sqliteDialect) BatchInsert(table string, columns []string, rows int) string {
+
+	return valuesInsert(d, table, columns, rows)
+}
+
+// sqlite 批量插入时 LastInsertId 返回最后一行的主键
+func (sqliteDialect) FirstInsertId(lastInsertId int64, rows int) int64 {
+
+	return lastInsertId - int64(rows) + 1
+}
+
+// sqlite 3.32 起为 32766，之前为 999，取较小值
+func (sqliteDialect) MaxBindVars() int { return 999 }
+
+func (d sqliteDialect) Upsert(table, seqKey string, columns, conflict, update []string) string {
+
+	return onConflict(valuesInsert(d, table, columns, 1), conflict, update)
+}
+
+// sqlite 以数据库文件加锁，没有行锁语法
+func (sqliteDialect) ForUpdate() string { return "" }
+
+// placeholders 返回从第 start+1 个开始的 n 个占位符，以逗号分隔
+func placeholders(d Dialect, start, n int) string {
+
+	buff := bytes.Buffer{}
+	for i := 1; i <= n; i++ {
+
+		if i > 1 {
+			buff.WriteString(",")
+		}
+		buff.WriteString(d.Placeholder(start + i))
+	}
+	return buff.String()
+}
+
+// valuesInsert 生成 INSERT INTO ... VALUES (...),(...) 形式的多行插入语句
+func valuesInsert(d Dialect, table string, columns []string, rows int) string {
+
+	buff := bytes.NewBufferString("INSERT INTO ")
+	buff.WriteString(table)
+	buff.WriteString(" (")
+	buff.WriteString(strings.Join(columns, ","))
+	buff.WriteString(") VALUES ")
+	for i := 0; i < rows; i++ {
+
+		if i > 0 {
+			buff.WriteString(",")
+		}
+		buff.WriteString("(")
+		buff.WriteString(placeholders(d, i*len(columns), len(columns)))
+		buff.WriteString(")")
+	}
+	return buff.String()
+}
+
+// onConflict 为插入语句加上 ON CONFLICT 子句，用于 PostgreSQL 和 SQLite
+func onConflict(sql string, conflict, update []string) string {
+
+	sql += " ON CONFLICT (" + strings.Join(conflict, ",") + ")"
+	if len(update) == 0 {
+		return sql + " DO NOTHING"
+	}
+
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = col + "=EXCLUDED." + col
+	}
+	return sql + " DO UPDATE SET " + strings.Join(sets, ",")
+}
@@ -0,0 +1,140 @@
+package oram
+
+import "testing"
+
+func TestDialectSQL(t *testing.T) {
+
+	const query = "SELECT * FROM t"
+	cols := []string{"a", "b"}
+
+	tests := []struct {
+		dialect   Dialect
+		paginate  string
+		limitOne  string
+		sequence  string
+		returning string
+		batch     string
+		upsert    string
+		ignore    string
+		forUpdate string
+	}{
+		{
+			Oracle,
+			"SELECT * FROM (SELECT TT.*, ROWNUM AS ROWNO FROM (SELECT * FROM t) TT  WHERE ROWNUM <= 30) TABLE_ALIAS WHERE TABLE_ALIAS.ROWNO > 20",
+			"SELECT * FROM (SELECT * FROM t) WHERE ROWNUM = 1",
+			"select seq_t.nextval from dual",
+			"",
+			"INSERT ALL INTO t (a,b) VALUES (:1,:2) INTO t (a,b) VALUES (:3,:4) SELECT 1 FROM DUAL",
+			"MERGE INTO t T USING (SELECT :1 AS a,:2 AS b FROM dual) S ON (T.a=S.a) WHEN MATCHED THEN UPDATE SET T.b=S.b WHEN NOT MATCHED THEN INSERT (a,b,id) VALUES (S.a,S.b,seq_t.nextval)",
+			"MERGE INTO t T USING (SELECT :1 AS a,:2 AS b FROM dual) S ON (T.a=S.a) WHEN NOT MATCHED THEN INSERT (a,b) VALUES (S.a,S.b)",
+			" FOR UPDATE",
+		},
+		{
+			MySQL,
+			"SELECT * FROM t LIMIT 20,10",
+			"SELECT * FROM t LIMIT 1",
+			"",
+			"",
+			"INSERT INTO t (a,b) VALUES (?,?),(?,?)",
+			"INSERT INTO t (a,b) VALUES (?,?) ON DUPLICATE KEY UPDATE b=VALUES(b)",
+			"INSERT IGNORE INTO t (a,b) VALUES (?,?)",
+			" FOR UPDATE",
+		},
+		{
+			PostgreSQL,
+			"SELECT * FROM t LIMIT 10 OFFSET 20",
+			"SELECT * FROM t LIMIT 1",
+			"",
+			" RETURNING id",
+			"INSERT INTO t (a,b) VALUES ($1,$2),($3,$4)",
+			"INSERT INTO t (a,b) VALUES ($1,$2) ON CONFLICT (a) DO UPDATE SET b=EXCLUDED.b",
+			"INSERT INTO t (a,b) VALUES ($1,$2) ON CONFLICT (a) DO NOTHING",
+			" FOR UPDATE",
+		},
+		{
+			SQLite,
+			"SELECT * FROM t LIMIT 10 OFFSET 20",
+			"SELECT * FROM t LIMIT 1",
+			"",
+			"",
+			"INSERT INTO t (a,b) VALUES (?,?),(?,?)",
+			"INSERT INTO t (a,b) VALUES (?,?) ON CONFLICT (a) DO UPDATE SET b=EXCLUDED.b",
+			"INSERT INTO t (a,b) VALUES (?,?) ON CONFLICT (a) DO NOTHING",
+			"",
+		},
+	}
+	for _, tt := range tests {
+
+		d := tt.dialect
+		check := func(what, got, want string) {
+			if got != want {
+				t.Errorf("%s %s = %q, want %q", d.Name(), what, got, want)
+			}
+		}
+		check("Paginate", d.Paginate(query, 20, 10), tt.paginate)
+		check("LimitOne", d.LimitOne(query), tt.limitOne)
+		check("Sequence", d.Sequence("t"), tt.sequence)
+		check("Returning", d.Returning("id"), tt.returning)
+		check("BatchInsert", d.BatchInsert("t", cols, 2), tt.batch)
+		check("Upsert", d.Upsert("t", "id", cols, []string{"a"}, []string{"b"}), tt.upsert)
+		check("Upsert ignore", d.Upsert("t", "", cols, []string{"a"}, nil), tt.ignore)
+		check("ForUpdate", d.ForUpdate(), tt.forUpdate)
+	}
+}
+
+type dialectPerson struct {
+	Uid  int64  `db:"uid" key:"auto"`
+	Name string `db:"name"`
+}
+
+func TestDialectStatements(t *testing.T) {
+
+	//Insert 的列按 map 遍历顺序排列，多列时列出每种顺序
+	tests := []struct {
+		dialect Dialect
+		want    [][]string
+	}{
+		{MySQL, [][]string{
+			{"SELECT * FROM tb_person WHERE uid=? [3]"},
+			{"INSERT INTO  tb_person (name) values (?) [a]"},
+		}},
+		{PostgreSQL, [][]string{
+			{"SELECT * FROM tb_person WHERE uid=$1 [3]"},
+			{"INSERT INTO  tb_person (name) values ($1) RETURNING uid [a]"},
+		}},
+		{Oracle, [][]string{
+			{"SELECT * FROM tb_person WHERE uid=:1 [3]"},
+			{"select seq_tb_person.nextval from dual []"},
+			{"INSERT INTO  tb_person (name,uid) values (:1,:2) [a 1]", "INSERT INTO  tb_person (uid,name) values (:1,:2) [1 a]"},
+		}},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(tt.dialect)
+
+		var p dialectPerson
+		db.Table("tb_person").FindById(&p, 3)
+		p = dialectPerson{Name: "a"}
+		if err := db.Table("tb_person").Insert(&p); err != nil {
+			t.Errorf("%s Insert: %v", tt.dialect.Name(), err)
+		}
+		if p.Uid == 0 {
+			t.Errorf("%s Insert didn't set Uid", tt.dialect.Name())
+		}
+
+		got := d.statements()
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s statements = %q, want %q", tt.dialect.Name(), got, tt.want)
+		}
+		for i := range got {
+
+			ok := false
+			for _, want := range tt.want[i] {
+				ok = ok || got[i] == want
+			}
+			if !ok {
+				t.Errorf("%s statement %d = %q, want %q", tt.dialect.Name(), i, got[i], tt.want[i])
+			}
+		}
+	}
+}
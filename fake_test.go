@@ -0,0 +1,200 @@
+package oram
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// fakeDriver 记录收到的语句，查询返回预先设置的结果，用于不连接数据库的测试
+type fakeDriver struct {
+	mu      sync.Mutex
+	log     []string
+	results []*fakeRows
+	failOn  string
+}
+
+type fakeRows struct {
+	cols []string
+	data [][]driver.Value
+	i    int
+}
+
+func (f *fakeRows) Columns() []string { return f.cols }
+
+func (f *fakeRows) Close() error { return nil }
+
+func (f *fakeRows) Next(dest []driver.Value) error {
+
+	if f.i >= len(f.data) {
+		return io.EOF
+	}
+	copy(dest, f.data[f.i])
+	f.i++
+	return nil
+}
+
+func (d *fakeDriver) record(s string) {
+
+	d.mu.Lock()
+	d.log = append(d.log, s)
+	d.mu.Unlock()
+}
+
+// statements 返回记录的语句
+func (d *fakeDriver) statements() []string {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.log...)
+}
+
+// errFor 语句含有 failOn 时返回错误
+func (d *fakeDriver) errFor(query string) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failOn != "" && strings.Contains(query, d.failOn) {
+		return errors.New("fake failure")
+	}
+	return nil
+}
+
+func (d *fakeDriver) next() *fakeRows {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.results) == 0 {
+		return &fakeRows{cols: []string{"n"}, data: [][]driver.Value{{int64(1)}}}
+	}
+	r := d.results[0]
+	d.results = d.results[1:]
+	return r
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+
+	if err := c.d.errFor("BEGIN"); err != nil {
+		return nil, err
+	}
+	begin := "BEGIN"
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		begin += " " + sql.IsolationLevel(opts.Isolation).String()
+	}
+	if opts.ReadOnly {
+		begin += " READ ONLY"
+	}
+	c.d.record(begin)
+	return &fakeTx{c.d}, nil
+}
+
+// CheckNamedValue 接受任意参数类型，由测试检查绑定的原值
+func (c *fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+
+	if v, ok := nv.Value.(driver.Valuer); ok {
+
+		val, err := v.Value()
+		nv.Value = val
+		return err
+	}
+	return nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t *fakeTx) Commit() error {
+
+	t.d.record("COMMIT")
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+
+	t.d.record("ROLLBACK")
+	return nil
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error { return nil }
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+
+	s.d.record(fmt.Sprintf("%s %v", s.query, args))
+	if err := s.d.errFor(s.query); err != nil {
+		return nil, err
+	}
+
+	//输出参数依次取下一个结果的第一行
+	var row []driver.Value
+	for _, a := range args {
+
+		out, ok := a.(sql.Out)
+		if !ok {
+			continue
+		}
+		if row == nil {
+			row = s.d.next().data[0]
+		}
+		reflect.ValueOf(out.Dest).Elem().Set(reflect.ValueOf(row[0]).Convert(reflect.TypeOf(out.Dest).Elem()))
+		row = row[1:]
+	}
+	return fakeResult{}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+
+	s.d.record(fmt.Sprintf("%s %v", s.query, args))
+	if err := s.d.errFor(s.query); err != nil {
+		return nil, err
+	}
+	return s.d.next(), nil
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 7, nil }
+
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+var fakeSeq int64
+
+// newFakeDB 返回使用独立 fakeDriver 的 ConDB
+func newFakeDB(dialect Dialect) (*ConDB, *fakeDriver) {
+
+	d := &fakeDriver{}
+	name := fmt.Sprintf("oram_fake_%d", atomic.AddInt64(&fakeSeq, 1))
+	sql.Register(name, d)
+
+	db, _ := sql.Open(name, "")
+	db.SetMaxOpenConns(1)
+	return NewDB(db, dialect), d
+}
@@ -0,0 +1,152 @@
+package oram
+
+import (
+	"context"
+	"reflect"
+)
+
+// 模型可以实现以下接口，在增删改查前后执行校验、填充字段、记录审计等逻辑。
+// 钩子收到的 ConDB 与当前操作使用同一个事务和 context，返回错误时中止操作，
+// 在 Transaction 中返回该错误即回滚事务
+
+type BeforeInserter interface {
+	BeforeInsert(db *ConDB) error
+}
+
+type AfterInserter interface {
+	AfterInsert(db *ConDB) error
+}
+
+type BeforeUpdater interface {
+	BeforeUpdate(db *ConDB) error
+}
+
+type AfterUpdater interface {
+	AfterUpdate(db *ConDB) error
+}
+
+type BeforeDeleter interface {
+	BeforeDelete(db *ConDB) error
+}
+
+type AfterDeleter interface {
+	AfterDelete(db *ConDB) error
+}
+
+type AfterFinder interface {
+	AfterFind(db *ConDB) error
+}
+
+var afterFinderType = reflect.TypeOf((*AfterFinder)(nil)).Elem()
+
+// Context 返回 ConDB 绑定的 context，没有绑定时为 context.Background()
+func (m *ConDB) Context() context.Context {
+
+	return m.context()
+}
+
+// hookDB 返回传给钩子的 ConDB，与当前操作共用事务和 context
+func (db *ConDB) hookDB() *ConDB {
+
+	root := db
+	if db.parent != nil {
+		root = db.parent
+	}
+	hook := root.session(db.tx, db.txs)
+	hook.ctx = db.ctx
+	return hook
+}
+
+// legacyHook 调用模型无参数的 PreInsert、PreUpdate 方法，在对应的 Before 钩子之前执行
+func legacyHook(i interface{}, name string) {
+
+	if mv := reflect.ValueOf(i).MethodByName(name); mv.IsValid() && mv.Type().NumIn() == 0 {
+		mv.Call(nil)
+	}
+}
+
+func (db *ConDB) beforeInsert(i interface{}) error {
+
+	if h, ok := i.(BeforeInserter); ok {
+		return h.BeforeInsert(db.hookDB())
+	}
+	return nil
+}
+
+func (db *ConDB) afterInsert(i interface{}) error {
+
+	if h, ok := i.(AfterInserter); ok {
+		return h.AfterInsert(db.hookDB())
+	}
+	return nil
+}
+
+func (db *ConDB) beforeUpdate(i interface{}) error {
+
+	if h, ok := i.(BeforeUpdater); ok {
+		return h.BeforeUpdate(db.hookDB())
+	}
+	return nil
+}
+
+func (db *ConDB) afterUpdate(i interface{}) error {
+
+	if h, ok := i.(AfterUpdater); ok {
+		return h.AfterUpdate(db.hookDB())
+	}
+	return nil
+}
+
+func (db *ConDB) beforeDelete(i interface{}) error {
+
+	if h, ok := i.(BeforeDeleter); ok {
+		return h.BeforeDelete(db.hookDB())
+	}
+	return nil
+}
+
+func (db *ConDB) afterDelete(i interface{}) error {
+
+	if h, ok := i.(AfterDeleter); ok {
+		return h.AfterDelete(db.hookDB())
+	}
+	return nil
+}
+
+// afterFind 对查询结果执行 AfterFind，out 为结构体指针或结构体切片指针
+func (db *ConDB) afterFind(out interface{}) error {
+
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+
+		if h, ok := out.(AfterFinder); ok {
+			return h.AfterFind(db.hookDB())
+		}
+		return nil
+	}
+
+	list := v.Elem()
+	elem := list.Type().Elem()
+	if elem.Kind() != reflect.Ptr {
+		elem = reflect.PtrTo(elem)
+	}
+	if !elem.Implements(afterFinderType) {
+		return nil
+	}
+
+	hook := db.hookDB()
+	for i := 0; i < list.Len(); i++ {
+
+		item := list.Index(i)
+		if item.Kind() != reflect.Ptr {
+			item = item.Addr()
+		}
+		if item.IsNil() {
+			continue
+		}
+		if err := item.Interface().(AfterFinder).AfterFind(hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package oram
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type hookPerson struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+
+	log  *[]string
+	veto string
+}
+
+func (p *hookPerson) add(s string) error {
+
+	*p.log = append(*p.log, s)
+	if p.veto == s {
+		return errors.New(s + " vetoed")
+	}
+	return nil
+}
+
+func (p *hookPerson) PreInsert() { p.add("PreInsert") }
+
+func (p *hookPerson) PreUpdate() { p.add("PreUpdate") }
+
+func (p *hookPerson) BeforeInsert(db *ConDB) error { return p.add("BeforeInsert") }
+
+func (p *hookPerson) AfterInsert(db *ConDB) error { return p.add("AfterInsert") }
+
+func (p *hookPerson) BeforeUpdate(db *ConDB) error { return p.add("BeforeUpdate") }
+
+func (p *hookPerson) AfterUpdate(db *ConDB) error { return p.add("AfterUpdate") }
+
+func (p *hookPerson) BeforeDelete(db *ConDB) error { return p.add("BeforeDelete") }
+
+func (p *hookPerson) AfterDelete(db *ConDB) error { return p.add("AfterDelete") }
+
+func (p *hookPerson) AfterFind(db *ConDB) error { return p.add("AfterFind") }
+
+func TestHooks(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		veto  string
+		run   func(db *ConDB, p *hookPerson) error
+		hooks []string
+		stmts int
+	}{
+		{"Insert", "", func(db *ConDB, p *hookPerson) error {
+			return db.Insert(p)
+		}, []string{"PreInsert", "BeforeInsert", "AfterInsert"}, 1},
+		{"InsertBatch", "", func(db *ConDB, p *hookPerson) error {
+			return db.InsertBatch([]*hookPerson{p}, 0)
+		}, []string{"PreInsert", "BeforeInsert", "AfterInsert"}, 1},
+		{"Flush", "", func(db *ConDB, p *hookPerson) error {
+			p.Id = 3
+			return db.Flush(p)
+		}, []string{"PreUpdate", "BeforeUpdate", "AfterUpdate"}, 1},
+		{"Update", "", func(db *ConDB, p *hookPerson) error {
+			return db.Model(p).Where("id=?", 3).Update("name=?", "b")
+		}, []string{"BeforeUpdate", "AfterUpdate"}, 1},
+		{"Delete", "", func(db *ConDB, p *hookPerson) error {
+			return db.Model(p).Where("id=?", 3).Delete()
+		}, []string{"BeforeDelete", "AfterDelete"}, 1},
+		{"Get", "", func(db *ConDB, p *hookPerson) error {
+			return db.Where("id=?", 1).Get(p)
+		}, []string{"AfterFind"}, 1},
+		{"Insert vetoed", "BeforeInsert", func(db *ConDB, p *hookPerson) error {
+			return db.Insert(p)
+		}, []string{"PreInsert", "BeforeInsert"}, 0},
+		{"InsertBatch vetoed", "BeforeInsert", func(db *ConDB, p *hookPerson) error {
+			return db.InsertBatch([]*hookPerson{p}, 0)
+		}, []string{"PreInsert", "BeforeInsert"}, 0},
+		{"Flush vetoed", "BeforeUpdate", func(db *ConDB, p *hookPerson) error {
+			p.Id = 3
+			return db.Flush(p)
+		}, []string{"PreUpdate", "BeforeUpdate"}, 0},
+		{"Delete vetoed", "BeforeDelete", func(db *ConDB, p *hookPerson) error {
+			return db.Model(p).Where("id=?", 3).Delete()
+		}, []string{"BeforeDelete"}, 0},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(MySQL)
+		d.results = []*fakeRows{{cols: []string{"id", "name"}, data: [][]driver.Value{{int64(1), "a"}}}}
+
+		var log []string
+		p := &hookPerson{Name: "a", log: &log, veto: tt.veto}
+		err := tt.run(db.Table("tb_person"), p)
+		if (err != nil) != (tt.veto != "") {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(log, tt.hooks) {
+			t.Errorf("%s: hooks = %v, want %v", tt.name, log, tt.hooks)
+		}
+		if n := len(d.statements()); n != tt.stmts {
+			t.Errorf("%s: %d statements, want %d", tt.name, n, tt.stmts)
+		}
+	}
+}
+
+type hookAudit struct {
+	Id     int64  `db:"id"`
+	Action string `db:"action"`
+}
+
+type hookOrder struct {
+	Id   int64 `db:"id"`
+	Paid bool  `db:"paid"`
+}
+
+func (o *hookOrder) AfterUpdate(db *ConDB) error {
+
+	return db.Table("tb_audit").Insert(&hookAudit{Action: "paid"})
+}
+
+func TestHookSharesTransaction(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	err := db.Transaction(func(tx *ConDB) error {
+		return tx.Table("tb_order").Flush(&hookOrder{Id: 1, Paid: true})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := d.statements()
+	if len(s) != 4 || s[0] != "BEGIN" || s[3] != "COMMIT" {
+		t.Errorf("statements = %q, want the audit insert inside the transaction", s)
+	}
+}
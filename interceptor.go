@@ -0,0 +1,174 @@
+package oram
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 语句的操作类型
+const (
+	OpSelect = "select"
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpExec   = "exec"
+)
+
+// Statement 一次发往数据库的语句，拦截器可以在调用 next 之前修改 SQL、Args 和 Context，
+// next 返回后 Duration、RowsAffected 和 Err 为执行结果。Args 中的敏感参数打印为 ***
+type Statement struct {
+	Context context.Context
+	// Op 操作类型，取值为 OpSelect、OpInsert、OpUpdate、OpDelete、OpExec
+	Op    string
+	Table string
+	SQL   string
+	Args  []interface{}
+	// Duration 数据库执行耗时，查询不包含读取结果行的时间
+	Duration time.Duration
+	// RowsAffected 写语句影响的行数，查询语句为 -1
+	RowsAffected int64
+	Err          error
+
+	result sql.Result
+	rows   *sql.Rows
+	row    *sql.Row
+}
+
+// Handler 执行语句并返回错误
+type Handler func(stmt *Statement) error
+
+// Interceptor 包裹每一条语句的执行，调用 next 继续执行，不调用 next 并返回错误即拒绝执行
+type Interceptor func(stmt *Statement, next Handler) error
+
+// config 同一个初始化的 ConDB 及其复制出的 ConDB、事务共用的配置
+type config struct {
+	interceptors []Interceptor
+
+	mu        sync.RWMutex
+	logger    Logger
+	slow      time.Duration
+	sensitive []*regexp.Regexp
+	metrics   *Metrics
+	tracer    Tracer
+}
+
+func (m *ConDB) config() *config {
+
+	if m.conf == nil {
+		m.conf = &config{}
+	}
+	return m.conf
+}
+
+// Use 注册拦截器，按注册顺序由外到内执行，初始化时设置一次
+func (m *ConDB) Use(interceptors ...Interceptor) {
+
+	c := m.config()
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// statementOp 根据语句的第一个关键字判断操作类型
+func statementOp(query string) string {
+
+	query = strings.TrimLeft(query, " \t\r\n(")
+	if i := strings.IndexAny(query, " \t\r\n("); i > 0 {
+		query = query[:i]
+	}
+
+	switch strings.ToLower(query) {
+	case "select":
+		return OpSelect
+	case "insert", "merge":
+		return OpInsert
+	case "update":
+		return OpUpdate
+	case "delete":
+		return OpDelete
+	}
+	return OpExec
+}
+
+// run 依次经过拦截器后调用 exec 执行语句
+func (m *ConDB) run(query string, args []interface{}, exec Handler) *Statement {
+
+	registerModel(m.model)
+
+	stmt := &Statement{
+		Op:           statementOp(query),
+		Table:        m.table,
+		SQL:          query,
+		Args:         m.redactArgs(query, args),
+		RowsAffected: -1,
+	}
+
+	ctx, span := m.startSpan(m.context(), spanName(stmt))
+	stmt.Context = ctx
+
+	h := func(s *Statement) error {
+
+		if s.Err = m.beginErr(); s.Err != nil {
+			return s.Err
+		}
+		start := time.Now()
+		s.Err = exec(s)
+		s.Duration = time.Since(start)
+		return s.Err
+	}
+
+	if m.conf != nil {
+
+		for i := len(m.conf.interceptors) - 1; i >= 0; i-- {
+
+			interceptor, next := m.conf.interceptors[i], h
+			h = func(s *Statement) error {
+				return interceptor(s, next)
+			}
+		}
+	}
+
+	stmt.Err = h(stmt)
+
+	span.SetAttribute(AttrDBStatement, stmt.SQL)
+	span.SetAttribute(AttrDBOperation, stmt.Op)
+	if stmt.Table != "" {
+		span.SetAttribute(AttrDBTable, stmt.Table)
+	}
+	if stmt.RowsAffected >= 0 {
+		span.SetAttribute(AttrRowsAffected, stmt.RowsAffected)
+	}
+	endSpan(span, stmt.Err)
+
+	m.logStatement(stmt)
+	if metrics := m.getMetrics(); metrics != nil {
+		metrics.observe(stmt)
+	}
+	return stmt
+}
+
+// Row 为 QueryRow 返回的单行结果，拦截器拒绝执行时 Scan、Err 返回拦截器的错误
+type Row struct {
+	row *sql.Row
+	err error
+}
+
+// Scan 同 sql.Row 的 Scan
+func (r *Row) Scan(dest ...interface{}) error {
+
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Scan(dest...)
+}
+
+// Err 同 sql.Row 的 Err
+func (r *Row) Err() error {
+
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Err()
+}
@@ -0,0 +1,100 @@
+package oram
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	var order []string
+	var last *Statement
+	db.Use(func(stmt *Statement, next Handler) error {
+
+		order = append(order, "outer")
+		err := next(stmt)
+		order = append(order, "outer done")
+		last = stmt
+		return err
+	}, func(stmt *Statement, next Handler) error {
+
+		order = append(order, "inner")
+		stmt.SQL = "/* traced */ " + stmt.SQL
+		return next(stmt)
+	})
+
+	if err := db.Table("tb_person").Where("id=?", 1).Delete(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"outer", "inner", "outer done"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+	if want := []string{"/* traced */ DELETE  FROM tb_person WHERE id=? [1]"}; !reflect.DeepEqual(d.statements(), want) {
+		t.Errorf("statements = %q, want %q", d.statements(), want)
+	}
+	if last.Op != OpDelete || last.Table != "tb_person" || last.RowsAffected != 1 || last.Duration <= 0 {
+		t.Errorf("statement = %+v", last)
+	}
+}
+
+func TestInterceptorVeto(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	errDenied := errors.New("denied")
+	db.Use(func(stmt *Statement, next Handler) error {
+
+		if stmt.Op == OpDelete || strings.Contains(stmt.SQL, "secret") {
+			return errDenied
+		}
+		return next(stmt)
+	})
+
+	if err := db.Table("tb_person").Where("id=?", 1).Delete(); err != errDenied {
+		t.Errorf("Delete: err = %v, want %v", err, errDenied)
+	}
+
+	var n int
+	row := db.QueryRow("select secret from tb_person")
+	if err := row.Scan(&n); err != errDenied {
+		t.Errorf("QueryRow Scan: err = %v, want %v", err, errDenied)
+	}
+	if err := row.Err(); err != errDenied {
+		t.Errorf("QueryRow Err: err = %v, want %v", err, errDenied)
+	}
+
+	if s := d.statements(); len(s) != 0 {
+		t.Errorf("vetoed statements reached the driver: %q", s)
+	}
+
+	//未拒绝的语句正常执行
+	if err := db.QueryRow("select count(*) from tb_person").Scan(&n); err != nil || n != 1 {
+		t.Errorf("QueryRow = %d, %v", n, err)
+	}
+}
+
+func TestStatementOp(t *testing.T) {
+
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT * FROM tb_person", OpSelect},
+		{"  (select 1)", OpSelect},
+		{"INSERT INTO tb_person (name) VALUES (?)", OpInsert},
+		{"MERGE INTO tb_person T USING", OpInsert},
+		{"update tb_person set name=?", OpUpdate},
+		{"DELETE FROM tb_person", OpDelete},
+		{"SAVEPOINT oram_sp_1", OpExec},
+	}
+	for _, tt := range tests {
+
+		if got := statementOp(tt.query); got != tt.want {
+			t.Errorf("statementOp(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
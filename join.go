@@ -0,0 +1,146 @@
+package oram
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+type join struct {
+	kind  string
+	table string
+	on    string
+	args  []interface{}
+}
+
+// Join 内连接 table，table 可以带别名，如 Join("tb_order o", "o.userid=p.userid AND o.status=?", 1)
+func (m *ConDB) Join(table, on string, args ...interface{}) *ConDB {
+
+	return m.join("JOIN", table, on, args)
+}
+
+// LeftJoin 左连接 table，用法与 Join 相同
+func (m *ConDB) LeftJoin(table, on string, args ...interface{}) *ConDB {
+
+	return m.join("LEFT JOIN", table, on, args)
+}
+
+// RightJoin 右连接 table，用法与 Join 相同
+func (m *ConDB) RightJoin(table, on string, args ...interface{}) *ConDB {
+
+	return m.join("RIGHT JOIN", table, on, args)
+}
+
+func (m *ConDB) join(kind, table, on string, args []interface{}) *ConDB {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+
+	db.joins = append(db.joins, join{kind: kind, table: table, on: on, args: args})
+	return db
+}
+
+// As 设置主表别名，如 db.Model(Person{}).As("p").Join("tb_order o", "o.userid=p.userid")
+func (m *ConDB) As(alias string) *ConDB {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+
+	db.alias = alias
+	return db
+}
+
+// qualifier 返回主表在语句中的名称，有别名时为别名
+func (db *ConDB) qualifier() string {
+
+	if db.alias != "" {
+		return db.alias
+	}
+	table := strings.TrimSpace(db.table)
+	if i := strings.LastIndexAny(table, " \t"); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
+
+// from 返回 FROM 之后的表或子查询和连接，子查询和 ON 中的参数在 buildSql 中排在条件参数之前
+func (db *ConDB) from() string {
+
+	db.joinParams = db.joinParams[:0]
+
+	s := bytes.Buffer{}
+	if db.source != nil {
+
+		query, args := db.source.Build()
+		s.WriteString("(" + db.Conver(query) + ")")
+		db.joinParams = append(db.joinParams, args...)
+	} else {
+		s.WriteString(db.table)
+	}
+	if db.alias != "" {
+		s.WriteString(" ")
+		s.WriteString(db.alias)
+	}
+
+	for _, j := range db.joins {
+
+		on, args := expandSubqueries(j.on, j.args)
+		s.WriteString(" ")
+		s.WriteString(j.kind)
+		s.WriteString(" ")
+		s.WriteString(j.table)
+		s.WriteString(" ON ")
+		s.WriteString(db.Conver(on))
+		db.joinParams = append(db.joinParams, args...)
+	}
+	return s.String()
+}
+
+// selectFields 返回查询的列，有连接且没有指定 Select 时按 out 的字段生成列名，避免多表同名列冲突：
+// 字段 db:"name" 读取主表的 name，db:"o.amount" 读取表 o 的 amount
+func (db *ConDB) selectFields(out interface{}) string {
+
+	if len(db.joins) == 0 || db.field != "*" {
+		return db.field
+	}
+
+	t := reflect.TypeOf(out)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if !isStruct(t) {
+		return db.field
+	}
+
+	model := getModel(t)
+	if len(model.fields) == 0 {
+		return db.field
+	}
+
+	fields := make([]string, len(model.fields))
+	for i, f := range model.fields {
+
+		if strings.Contains(f.column, ".") {
+			fields[i] = f.column + " AS " + joinColumn(f.column)
+		} else {
+			fields[i] = db.qualifier() + "." + f.column
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// joinColumn 返回带表名的列在查询结果中的列名，如 o.amount 为 o_amount
+func joinColumn(column string) string {
+
+	return strings.Replace(column, ".", "_", -1)
+}
@@ -0,0 +1,105 @@
+package oram
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type joinPerson struct {
+	Id     int64   `db:"id"`
+	Name   string  `db:"name"`
+	Amount float64 `db:"o.amount"`
+}
+
+func TestJoinFind(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+	d.results = []*fakeRows{{
+		cols: []string{"id", "name", "o_amount"},
+		data: [][]driver.Value{{int64(1), "a", 9.5}},
+	}}
+
+	var list []joinPerson
+	err := db.Table("tb_person").As("p").
+		LeftJoin("tb_order o", "o.userid=p.userid AND o.status=?", 1).
+		Where("p.bankid=?", 3).Find(&list).Err
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"SELECT p.id,p.name,o.amount AS o_amount FROM tb_person p LEFT JOIN tb_order o ON o.userid=p.userid AND o.status=? WHERE p.bankid=? [1 3]"}
+	if !reflect.DeepEqual(d.statements(), want) {
+		t.Errorf("statements = %q, want %q", d.statements(), want)
+	}
+	if want := []joinPerson{{Id: 1, Name: "a", Amount: 9.5}}; !reflect.DeepEqual(list, want) {
+		t.Errorf("list = %+v, want %+v", list, want)
+	}
+}
+
+func TestJoinCount(t *testing.T) {
+
+	db, d := newFakeDB(Oracle)
+
+	db.Table("tb_person p").Join("tb_order o", "o.userid=p.userid AND o.status=?", 1).Where("o.amount>?", 100).Count()
+
+	want := []string{"SELECT count(*) FROM tb_person p JOIN tb_order o ON o.userid=p.userid AND o.status=:1 WHERE o.amount>:2 [1 100]"}
+	if !reflect.DeepEqual(d.statements(), want) {
+		t.Errorf("statements = %q, want %q", d.statements(), want)
+	}
+}
+
+func TestJoinColumnsNotWritten(t *testing.T) {
+
+	db, d := newFakeDB(PostgreSQL)
+
+	if err := db.Insert(&joinPerson{Name: "a", Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Upsert(&joinPerson{Name: "a", Amount: 1}, "name"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.statements()) == 0 {
+		t.Fatal("no statements")
+	}
+	for _, s := range d.statements() {
+
+		if strings.Contains(s, "amount") {
+			t.Errorf("join column written: %q", s)
+		}
+	}
+}
+
+func TestJoinMapColumns(t *testing.T) {
+
+	rows := func() []*fakeRows {
+		return []*fakeRows{{
+			cols: []string{"id", "name", "id"},
+			data: [][]driver.Value{{"1", "a", "2"}},
+		}}
+	}
+
+	db, d := newFakeDB(MySQL)
+
+	//有连接时保留第一个即主表的列
+	d.results = rows()
+	list, err := db.Table("tb_person p").Join("tb_order o", "o.userid=p.userid").List()
+	if err != nil || len(list) != 1 || list[0]["id"] != "1" {
+		t.Errorf("List with join = %v, %v", list, err)
+	}
+
+	//原生 SQL 保留最后一个
+	d.results = rows()
+	maps, err := db.QueryMaps("select * from tb_person p, tb_order o")
+	if err != nil || len(maps) != 1 || maps[0]["id"] != "2" {
+		t.Errorf("QueryMaps = %v, %v", maps, err)
+	}
+
+	d.results = rows()
+	m, err := db.QueryMap("select * from tb_person p, tb_order o")
+	if err != nil || m["id"] != "2" {
+		t.Errorf("QueryMap = %v, %v", m, err)
+	}
+}
@@ -0,0 +1,128 @@
+package oram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LogLevel 日志级别，取值与 log/slog 的 Level 相同，可以直接转换为 slog.Level
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// Logger 结构化日志接口，keyvals 为键值交替的属性，与 slog.Logger.Log 的参数形式相同
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc 把函数转换为 Logger，例如适配 slog：
+//
+//	db.SetLogger(oram.LoggerFunc(func(ctx context.Context, level oram.LogLevel, msg string, keyvals ...interface{}) {
+//		slog.Log(ctx, slog.Level(level), msg, keyvals...)
+//	}))
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+
+	f(ctx, level, msg, keyvals...)
+}
+
+// printfLogger 把结构化日志以文本形式输出到 SqlLogger，供 TraceOn 使用
+type printfLogger struct {
+	prefix string
+	out    SqlLogger
+}
+
+func (p printfLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+
+	buff := strings.Builder{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+
+		buff.WriteString(" ")
+		buff.WriteString(fmt.Sprint(keyvals[i]))
+		buff.WriteString("=")
+		buff.WriteString(fmt.Sprint(keyvals[i+1]))
+	}
+	p.out.Printf("%s%s %s%s", p.prefix, level, msg, buff.String())
+}
+
+// SetLogger 设置日志，同一个初始化的 ConDB 复制出的 ConDB 和事务共用，l 为 nil 时关闭日志
+func (m *ConDB) SetLogger(l Logger) {
+
+	c := m.config()
+	c.mu.Lock()
+	c.logger = l
+	c.mu.Unlock()
+}
+
+// SetSlowThreshold 设置慢查询阈值，执行时间超过 d 的语句以 LevelWarn 记录，d <= 0 时不区分慢查询
+func (m *ConDB) SetSlowThreshold(d time.Duration) {
+
+	c := m.config()
+	c.mu.Lock()
+	c.slow = d
+	c.mu.Unlock()
+}
+
+func (m *ConDB) getLogger() (Logger, time.Duration) {
+
+	if m.conf == nil {
+		return nil, 0
+	}
+
+	m.conf.mu.RLock()
+	defer m.conf.mu.RUnlock()
+
+	return m.conf.logger, m.conf.slow
+}
+
+// logStatement 在语句执行后记录 SQL、参数、耗时、影响行数和错误
+func (m *ConDB) logStatement(stmt *Statement) {
+
+	logger, slow := m.getLogger()
+	if logger == nil {
+		return
+	}
+
+	level, msg := LevelInfo, "query"
+	if stmt.Err != nil {
+		level, msg = LevelError, "query error"
+	} else if slow > 0 && stmt.Duration >= slow {
+		level, msg = LevelWarn, "slow query"
+	}
+
+	keyvals := []interface{}{
+		"op", stmt.Op,
+		"table", stmt.Table,
+		"sql", stmt.SQL,
+		"args", argsToStr(stmt.Args...),
+		"elapsed", stmt.Duration,
+	}
+	if stmt.RowsAffected >= 0 {
+		keyvals = append(keyvals, "rows", stmt.RowsAffected)
+	}
+	if stmt.Err != nil {
+		keyvals = append(keyvals, "error", stmt.Err)
+	}
+	logger.Log(stmt.Context, level, msg, keyvals...)
+}
@@ -0,0 +1,118 @@
+package oram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type logEntry struct {
+	level   LogLevel
+	msg     string
+	keyvals map[string]interface{}
+}
+
+func recordLogs(db *ConDB) *[]logEntry {
+
+	var logs []logEntry
+	db.SetLogger(LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+
+		e := logEntry{level: level, msg: msg, keyvals: make(map[string]interface{})}
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			e.keyvals[keyvals[i].(string)] = keyvals[i+1]
+		}
+		logs = append(logs, e)
+	}))
+	return &logs
+}
+
+func TestLogger(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+	logs := recordLogs(db)
+
+	db.Table("tb_person").Where("id=?", 1).Delete()
+
+	d.failOn = "UPDATE"
+	db.Table("tb_person").Where("id=?", 1).Update("name=?", "b")
+
+	d.failOn = ""
+	db.SetSlowThreshold(time.Nanosecond)
+	db.Table("tb_person").Count()
+
+	if len(*logs) != 3 {
+		t.Fatalf("got %d log entries, want 3", len(*logs))
+	}
+
+	info, failed, slow := (*logs)[0], (*logs)[1], (*logs)[2]
+	if info.level != LevelInfo || info.msg != "query" {
+		t.Errorf("delete logged as %s %q", info.level, info.msg)
+	}
+	if info.keyvals["op"] != OpDelete || info.keyvals["table"] != "tb_person" || info.keyvals["rows"] != int64(1) {
+		t.Errorf("delete keyvals = %v", info.keyvals)
+	}
+	if _, ok := info.keyvals["error"]; ok {
+		t.Errorf("delete logged an error: %v", info.keyvals)
+	}
+
+	if failed.level != LevelError || failed.msg != "query error" || failed.keyvals["error"] == nil {
+		t.Errorf("failed update logged as %s %q %v", failed.level, failed.msg, failed.keyvals)
+	}
+
+	if slow.level != LevelWarn || slow.msg != "slow query" {
+		t.Errorf("slow count logged as %s %q", slow.level, slow.msg)
+	}
+	if _, ok := slow.keyvals["rows"]; ok {
+		t.Errorf("select logged rows: %v", slow.keyvals)
+	}
+
+	//关闭后不再记录
+	db.TraceOff()
+	db.Table("tb_person").Count()
+	if len(*logs) != 3 {
+		t.Errorf("logged after TraceOff: %d entries", len(*logs))
+	}
+}
+
+type printfRecorder []string
+
+func (p *printfRecorder) Printf(format string, v ...interface{}) {
+
+	*p = append(*p, fmt.Sprintf(format, v...))
+}
+
+func TestTraceOn(t *testing.T) {
+
+	db, _ := newFakeDB(MySQL)
+
+	var out printfRecorder
+	db.TraceOn("[oram]", &out)
+	db.Table("tb_person").Where("id=?", 1).Delete()
+
+	if len(out) != 1 {
+		t.Fatalf("got %d lines, want 1", len(out))
+	}
+	want := "[oram] INFO query op=delete table=tb_person sql=DELETE  FROM tb_person WHERE id=? args=1:1 elapsed="
+	if !strings.HasPrefix(out[0], want) || !strings.HasSuffix(out[0], " rows=1") {
+		t.Errorf("line = %q, want prefix %q", out[0], want)
+	}
+}
+
+func TestLogLevelString(t *testing.T) {
+
+	tests := map[LogLevel]string{
+		LevelDebug:    "DEBUG",
+		LevelInfo:     "INFO",
+		LevelWarn:     "WARN",
+		LevelError:    "ERROR",
+		LevelWarn + 1: "WARN",
+	}
+	for level, want := range tests {
+
+		if got := level.String(); got != want {
+			t.Errorf("LogLevel(%d) = %q, want %q", int(level), got, want)
+		}
+	}
+}
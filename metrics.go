@@ -0,0 +1,267 @@
+package oram
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets 默认的耗时直方图分桶，单位秒
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics 按操作类型和表统计语句数量、错误数量和耗时，通过 SetMetrics 启用
+type Metrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[metricKey]*metricSeries
+	db      *sql.DB
+}
+
+type metricKey struct {
+	op    string
+	table string
+}
+
+type metricSeries struct {
+	count   uint64
+	errors  uint64
+	sum     float64
+	buckets []uint64 // 落在每个分桶内的数量，不累计
+}
+
+// QueryStats 某个操作类型和表的统计，Buckets 为累计数量，与 Metrics 的分桶一一对应
+type QueryStats struct {
+	Op       string
+	Table    string
+	Count    uint64
+	Errors   uint64
+	Duration time.Duration
+	Buckets  []uint64
+}
+
+// MetricsSnapshot Metrics 在某一时刻的统计
+type MetricsSnapshot struct {
+	Buckets []float64
+	Queries []QueryStats
+	Pool    sql.DBStats
+}
+
+// NewMetrics 创建统计，buckets 为耗时直方图的分桶上限，单位秒，为空时使用 DefaultBuckets
+func NewMetrics(buckets ...float64) *Metrics {
+
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	return &Metrics{buckets: b, series: make(map[metricKey]*metricSeries)}
+}
+
+// SetMetrics 启用统计，同一个初始化的 ConDB 复制出的 ConDB 和事务共用，c 为 nil 时关闭
+func (m *ConDB) SetMetrics(c *Metrics) {
+
+	if c != nil {
+
+		c.mu.Lock()
+		c.db = m.Db
+		c.mu.Unlock()
+	}
+
+	conf := m.config()
+	conf.mu.Lock()
+	conf.metrics = c
+	conf.mu.Unlock()
+}
+
+func (m *ConDB) getMetrics() *Metrics {
+
+	if m.conf == nil {
+		return nil
+	}
+
+	m.conf.mu.RLock()
+	defer m.conf.mu.RUnlock()
+
+	return m.conf.metrics
+}
+
+// observe 记录一条语句的执行结果
+func (c *Metrics) observe(stmt *Statement) {
+
+	seconds := stmt.Duration.Seconds()
+	key := metricKey{op: stmt.Op, table: stmt.Table}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.series[key]
+	if s == nil {
+		s = &metricSeries{buckets: make([]uint64, len(c.buckets))}
+		c.series[key] = s
+	}
+
+	s.count++
+	s.sum += seconds
+	if stmt.Err != nil {
+		s.errors++
+	}
+	if i := sort.SearchFloat64s(c.buckets, seconds); i < len(c.buckets) {
+		s.buckets[i]++
+	}
+}
+
+// Snapshot 返回当前的统计，Queries 按操作类型和表排序
+func (c *Metrics) Snapshot() MetricsSnapshot {
+
+	c.mu.Lock()
+	snap := MetricsSnapshot{Buckets: c.buckets, Queries: make([]QueryStats, 0, len(c.series))}
+	for key, s := range c.series {
+
+		q := QueryStats{
+			Op:       key.op,
+			Table:    key.table,
+			Count:    s.count,
+			Errors:   s.errors,
+			Duration: time.Duration(s.sum * float64(time.Second)),
+			Buckets:  make([]uint64, len(s.buckets)),
+		}
+		var total uint64
+		for i, n := range s.buckets {
+			total += n
+			q.Buckets[i] = total
+		}
+		snap.Queries = append(snap.Queries, q)
+	}
+	db := c.db
+	c.mu.Unlock()
+
+	if db != nil {
+		snap.Pool = db.Stats()
+	}
+
+	sort.Slice(snap.Queries, func(i, j int) bool {
+
+		if snap.Queries[i].Op != snap.Queries[j].Op {
+			return snap.Queries[i].Op < snap.Queries[j].Op
+		}
+		return snap.Queries[i].Table < snap.Queries[j].Table
+	})
+	return snap
+}
+
+// Reset 清空统计
+func (c *Metrics) Reset() {
+
+	c.mu.Lock()
+	c.series = make(map[metricKey]*metricSeries)
+	c.mu.Unlock()
+}
+
+// ServeHTTP 以 Prometheus 文本格式输出统计，可以直接注册到 http.ServeMux
+func (c *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo 以 Prometheus 文本格式把统计写入 w
+func (c *Metrics) WriteTo(w io.Writer) (int64, error) {
+
+	snap := c.Snapshot()
+	out := &countWriter{w: bufio.NewWriter(w)}
+
+	out.printf("# HELP oram_queries_total Number of statements executed.\n")
+	out.printf("# TYPE oram_queries_total counter\n")
+	for _, q := range snap.Queries {
+		out.printf("oram_queries_total{%s} %d\n", labels(q), q.Count)
+	}
+
+	out.printf("# HELP oram_query_errors_total Number of statements that returned an error.\n")
+	out.printf("# TYPE oram_query_errors_total counter\n")
+	for _, q := range snap.Queries {
+		out.printf("oram_query_errors_total{%s} %d\n", labels(q), q.Errors)
+	}
+
+	out.printf("# HELP oram_query_duration_seconds Statement execution time.\n")
+	out.printf("# TYPE oram_query_duration_seconds histogram\n")
+	for _, q := range snap.Queries {
+
+		l := labels(q)
+		for i, le := range snap.Buckets {
+			out.printf("oram_query_duration_seconds_bucket{%s,le=\"%s\"} %d\n", l, formatFloat(le), q.Buckets[i])
+		}
+		out.printf("oram_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, q.Count)
+		out.printf("oram_query_duration_seconds_sum{%s} %s\n", l, formatFloat(q.Duration.Seconds()))
+		out.printf("oram_query_duration_seconds_count{%s} %d\n", l, q.Count)
+	}
+
+	pool := snap.Pool
+	gauges := []struct {
+		name, help, typ string
+		value           string
+	}{
+		{"oram_pool_max_open_connections", "Maximum number of open connections.", "gauge", strconv.Itoa(pool.MaxOpenConnections)},
+		{"oram_pool_open_connections", "Number of established connections.", "gauge", strconv.Itoa(pool.OpenConnections)},
+		{"oram_pool_in_use_connections", "Number of connections currently in use.", "gauge", strconv.Itoa(pool.InUse)},
+		{"oram_pool_idle_connections", "Number of idle connections.", "gauge", strconv.Itoa(pool.Idle)},
+		{"oram_pool_wait_count_total", "Total number of connections waited for.", "counter", strconv.FormatInt(pool.WaitCount, 10)},
+		{"oram_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", "counter", formatFloat(pool.WaitDuration.Seconds())},
+		{"oram_pool_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", "counter", strconv.FormatInt(pool.MaxIdleClosed, 10)},
+		{"oram_pool_max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.", "counter", strconv.FormatInt(pool.MaxIdleTimeClosed, 10)},
+		{"oram_pool_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", "counter", strconv.FormatInt(pool.MaxLifetimeClosed, 10)},
+	}
+	for _, g := range gauges {
+
+		out.printf("# HELP %s %s\n", g.name, g.help)
+		out.printf("# TYPE %s %s\n", g.name, g.typ)
+		out.printf("%s %s\n", g.name, g.value)
+	}
+
+	if out.err == nil {
+		out.err = out.w.Flush()
+	}
+	return out.n, out.err
+}
+
+func labels(q QueryStats) string {
+
+	return `op="` + escapeLabel(q.Op) + `",table="` + escapeLabel(q.Table) + `"`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countWriter 记录写入的字节数和第一个错误
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) printf(format string, args ...interface{}) {
+
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
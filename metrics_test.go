@@ -0,0 +1,78 @@
+package oram
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsWriteTo(t *testing.T) {
+
+	c := NewMetrics(0.1, 0.01)
+	for _, s := range []*Statement{
+		{Op: OpSelect, Table: "tb_person", Duration: time.Second / 128},
+		{Op: OpSelect, Table: "tb_person", Duration: time.Second / 16},
+		{Op: OpSelect, Table: "tb_person", Duration: time.Second, Err: errors.New("timeout")},
+		{Op: OpInsert, Table: `tb_"x"`, Duration: 5 * time.Millisecond},
+	} {
+		c.observe(s)
+	}
+
+	buf := bytes.Buffer{}
+	n, err := c.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE oram_queries_total counter",
+		`oram_queries_total{op="select",table="tb_person"} 3`,
+		`oram_queries_total{op="insert",table="tb_\"x\""} 1`,
+		`oram_query_errors_total{op="select",table="tb_person"} 1`,
+		`oram_query_errors_total{op="insert",table="tb_\"x\""} 0`,
+		"# TYPE oram_query_duration_seconds histogram",
+		`oram_query_duration_seconds_bucket{op="select",table="tb_person",le="0.01"} 1`,
+		`oram_query_duration_seconds_bucket{op="select",table="tb_person",le="0.1"} 2`,
+		`oram_query_duration_seconds_bucket{op="select",table="tb_person",le="+Inf"} 3`,
+		`oram_query_duration_seconds_sum{op="select",table="tb_person"} 1.0703125`,
+		`oram_query_duration_seconds_count{op="select",table="tb_person"} 3`,
+		"# TYPE oram_pool_open_connections gauge",
+		"oram_pool_wait_count_total 0",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+
+	c.Reset()
+	if q := c.Snapshot().Queries; len(q) != 0 {
+		t.Errorf("%d series after Reset", len(q))
+	}
+}
+
+func TestMetricsObserveStatements(t *testing.T) {
+
+	db, _ := newFakeDB(MySQL)
+	c := NewMetrics()
+	db.SetMetrics(c)
+
+	db.Table("tb_person").Where("id=?", 1).Count()
+	db.Table("tb_person").Where("id=?", 1).Delete()
+
+	counts := map[string]uint64{}
+	for _, q := range c.Snapshot().Queries {
+		counts[q.Op+" "+q.Table] = q.Count
+	}
+	if counts["select tb_person"] != 1 || counts["delete tb_person"] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+	if open := c.Snapshot().Pool.MaxOpenConnections; open != 1 {
+		t.Errorf("pool max open = %d, want 1", open)
+	}
+}
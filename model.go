@@ -0,0 +1,149 @@
+package oram
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldMeta 结构体字段与数据库列的对应关系
+type fieldMeta struct {
+	name   string
+	column string
+	index  []int
+	typ    reflect.Type
+	auto   bool
+	// scanner 表示 *T 实现了 sql.Scanner，或字段本身是指针，读取时直接扫描到字段，由其处理 NULL
+	scanner bool
+	// valuer 表示只有 *T 实现了 driver.Valuer，写入时绑定字段地址
+	valuer bool
+	// readOnly 表示连接查询中其它表的列，如 db:"o.amount"，只读取不写入
+	readOnly bool
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// modelMeta 结构体解析后的元数据，每个类型只解析一次
+type modelMeta struct {
+	typ     reflect.Type
+	fields  []*fieldMeta
+	columns map[string]*fieldMeta
+	key     *fieldMeta
+}
+
+var models sync.Map
+
+// getModel 返回结构体类型的元数据，t 可以是结构体、结构体指针或切片
+func getModel(t reflect.Type) *modelMeta {
+
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if m, ok := models.Load(t); ok {
+		return m.(*modelMeta)
+	}
+
+	m := &modelMeta{typ: t, columns: make(map[string]*fieldMeta)}
+	if t.Kind() == reflect.Struct {
+		m.parse(t, nil)
+	}
+
+	if m.key == nil {
+		m.key = m.column("id")
+	}
+	if m.key == nil {
+		for _, f := range m.fields {
+			if f.name == "Id" {
+				m.key = f
+			}
+		}
+	}
+
+	actual, _ := models.LoadOrStore(t, m)
+	return actual.(*modelMeta)
+}
+
+func (m *modelMeta) parse(t reflect.Type, index []int) {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct { // 匿名字段结构
+
+			m.parse(f.Type, idx)
+			continue
+		}
+
+		col := f.Tag.Get("db")
+		if col == "" || col == "-" || f.PkgPath != "" {
+			continue
+		}
+
+		field := &fieldMeta{name: f.Name, column: col, index: idx, typ: f.Type, readOnly: strings.Contains(col, ".")}
+		field.scanner = f.Type.Kind() == reflect.Ptr || reflect.PtrTo(f.Type).Implements(scannerType)
+		field.valuer = !f.Type.Implements(valuerType) && reflect.PtrTo(f.Type).Implements(valuerType)
+		if f.Tag.Get("sensitive") == "true" {
+			registerSensitive(col)
+		}
+		if key := f.Tag.Get("key"); key != "" {
+
+			field.auto = key == "auto"
+			if m.key == nil {
+				m.key = field
+			}
+		}
+
+		m.fields = append(m.fields, field)
+		m.columns[col] = field
+		if lower := strings.ToLower(col); m.columns[lower] == nil {
+			m.columns[lower] = field
+		}
+		if field.readOnly { //连接查询中其它表的列，查询结果中的列名见 joinColumn
+			m.columns[strings.ToLower(joinColumn(col))] = field
+		}
+	}
+}
+
+// column 按列名查找字段，不区分大小写
+func (m *modelMeta) column(name string) *fieldMeta {
+
+	if f, ok := m.columns[name]; ok {
+		return f
+	}
+	return m.columns[strings.ToLower(name)]
+}
+
+// keyColumn 返回主键列名，没有定义主键时为 id
+func (m *modelMeta) keyColumn() string {
+
+	if m.key == nil {
+		return "id"
+	}
+	return m.key.column
+}
+
+// value 返回 v 中字段 f 的值，v 为结构体
+func (f *fieldMeta) value(v reflect.Value) reflect.Value {
+
+	return v.FieldByIndex(f.index)
+}
+
+// bind 返回写入数据库时字段的取值，值由 bindValue 转换为绑定参数
+func (f *fieldMeta) bind(v reflect.Value) interface{} {
+
+	field := f.value(v)
+	if f.valuer && field.CanAddr() {
+		return field.Addr().Interface()
+	}
+	return field.Interface()
+}
@@ -0,0 +1,96 @@
+package oram
+
+import (
+	"reflect"
+	"testing"
+)
+
+type modelBase struct {
+	Id      int64  `db:"id"`
+	Created string `db:"created"`
+}
+
+type modelPerson struct {
+	modelBase
+	Name   string `db:"Name"`
+	Skip   string `db:"-"`
+	NoTag  string
+	secret string `db:"secret"`
+}
+
+type modelAuto struct {
+	Uid  int64  `db:"uid" key:"auto"`
+	Name string `db:"name"`
+}
+
+type modelById struct {
+	Id   int64  `db:"pid"`
+	Name string `db:"name"`
+}
+
+func TestGetModel(t *testing.T) {
+
+	typ := reflect.TypeOf(modelPerson{})
+	m := getModel(typ)
+
+	for _, other := range []reflect.Type{reflect.TypeOf(&modelPerson{}), reflect.TypeOf([]modelPerson{}), reflect.TypeOf([]*modelPerson{})} {
+		if getModel(other) != m {
+			t.Errorf("getModel(%v) parsed again", other)
+		}
+	}
+
+	var columns []string
+	for _, f := range m.fields {
+		columns = append(columns, f.column)
+	}
+	if want := []string{"id", "created", "Name"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %q, want %q", columns, want)
+	}
+
+	tests := []struct {
+		column string
+		field  string
+	}{
+		{"id", "Id"},
+		{"CREATED", "Created"},
+		{"name", "Name"},
+		{"secret", ""},
+		{"Skip", ""},
+	}
+	for _, tt := range tests {
+
+		name := ""
+		if f := m.column(tt.column); f != nil {
+			name = f.name
+		}
+		if name != tt.field {
+			t.Errorf("column(%q) = %q, want %q", tt.column, name, tt.field)
+		}
+	}
+
+	p := modelPerson{modelBase: modelBase{Id: 3}}
+	if id := m.key.value(reflect.ValueOf(p)).Int(); id != 3 {
+		t.Errorf("key of embedded Id = %d, want 3", id)
+	}
+
+	keys := []struct {
+		v    interface{}
+		want string
+		auto bool
+	}{
+		{modelPerson{}, "id", false},
+		{modelAuto{}, "uid", true},
+		{modelById{}, "pid", false},
+		{struct{ Name string }{}, "id", false},
+	}
+	for _, tt := range keys {
+
+		m := getModel(reflect.TypeOf(tt.v))
+		if got := m.keyColumn(); got != tt.want {
+			t.Errorf("%T keyColumn = %q, want %q", tt.v, got, tt.want)
+		}
+		if m.key != nil && m.key.auto != tt.auto {
+			t.Errorf("%T key auto = %v, want %v", tt.v, m.key.auto, tt.auto)
+		}
+	}
+}
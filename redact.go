@@ -0,0 +1,322 @@
+package oram
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted 日志中替代敏感参数的文本
+const redacted = "***"
+
+// sensitiveValue 标记敏感的绑定参数，执行时以原值绑定，日志和拦截器中打印为 ***
+type sensitiveValue struct {
+	v interface{}
+}
+
+// Sensitive 把 v 标记为敏感参数，例如 db.Where("acc_no=?", oram.Sensitive(acc))
+func Sensitive(v interface{}) interface{} {
+
+	if _, ok := v.(sensitiveValue); ok {
+		return v
+	}
+	return sensitiveValue{v}
+}
+
+func (s sensitiveValue) String() string { return redacted }
+
+func (s sensitiveValue) GoString() string { return redacted }
+
+func (s sensitiveValue) Format(f fmt.State, verb rune) { f.Write([]byte(redacted)) }
+
+func (s sensitiveValue) Value() (driver.Value, error) {
+
+	return driver.DefaultParameterConverter.ConvertValue(s.v)
+}
+
+// plainArgs 返回去掉敏感标记的参数，用于实际执行
+func plainArgs(args []interface{}) []interface{} {
+
+	var out []interface{}
+	for i, a := range args {
+
+		s, ok := a.(sensitiveValue)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]interface{}, len(args))
+			copy(out, args)
+		}
+		out[i] = s.v
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// 结构体字段标记 sensitive:"true" 的列，所有语句中这些列的参数都按敏感参数处理
+var sensitiveColumns sync.Map
+
+func registerSensitive(column string) {
+
+	sensitiveColumns.Store(strings.ToLower(column), true)
+}
+
+// registerModel 在执行语句之前解析 v 的模型，登记其中标记为敏感的列，
+// v 可以是结构体、结构体指针或切片，其它类型忽略
+func registerModel(v interface{}) {
+
+	if v == nil {
+		return
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if isStruct(t) {
+		getModel(t)
+	}
+}
+
+// RegisterModel 预先登记模型中标记为敏感的列，用于不带结构体的查询，如 db.Table("t").Where("card_no=?", no).Count()
+func RegisterModel(models ...interface{}) {
+
+	for _, v := range models {
+		registerModel(v)
+	}
+}
+
+// SetSensitive 设置敏感列，每一项为匹配列名的正则表达式，不区分大小写，
+// 如 "acc_no"、"phone|mobile"，语句中这些列的参数在日志和拦截器中打印为 ***
+func (m *ConDB) SetSensitive(patterns ...string) error {
+
+	list := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+
+		re, err := regexp.Compile("(?i)^(?:" + p + ")$")
+		if err != nil {
+			return err
+		}
+		list = append(list, re)
+	}
+
+	c := m.config()
+	c.mu.Lock()
+	c.sensitive = list
+	c.mu.Unlock()
+	return nil
+}
+
+func (m *ConDB) isSensitive(column string) bool {
+
+	if _, ok := sensitiveColumns.Load(strings.ToLower(column)); ok {
+		return true
+	}
+	if m.conf == nil {
+		return false
+	}
+
+	m.conf.mu.RLock()
+	defer m.conf.mu.RUnlock()
+
+	for _, re := range m.conf.sensitive {
+		if re.MatchString(column) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ConDB) hasSensitive() bool {
+
+	if m.conf != nil {
+
+		m.conf.mu.RLock()
+		n := len(m.conf.sensitive)
+		m.conf.mu.RUnlock()
+		if n > 0 {
+			return true
+		}
+	}
+
+	has := false
+	sensitiveColumns.Range(func(k, v interface{}) bool {
+		has = true
+		return false
+	})
+	return has
+}
+
+// redactArgs 按语句中每个占位符对应的列，把敏感列的参数标记为敏感参数
+func (m *ConDB) redactArgs(query string, args []interface{}) []interface{} {
+
+	if len(args) == 0 || !m.hasSensitive() {
+		return args
+	}
+
+	columns := placeholderColumns(query)
+	var out []interface{}
+	for i, col := range columns {
+
+		if i >= len(args) {
+			break
+		}
+		if col == "" || !m.isSensitive(col) {
+			continue
+		}
+		switch args[i].(type) {
+		case sensitiveValue, sql.Out, sql.NamedArg:
+			continue
+		}
+		if out == nil {
+			out = make([]interface{}, len(args))
+			copy(out, args)
+		}
+		out[i] = sensitiveValue{args[i]}
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// 占位符前出现时不作为列名的关键字
+var sqlKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true, "NULL": true,
+	"LIKE": true, "BETWEEN": true, "ESCAPE": true, "SET": true, "WHERE": true,
+}
+
+// placeholderColumns 按出现顺序返回语句中每个占位符对应的列名，无法判断时为空。
+// INSERT 的 VALUES 按列表顺序对应，SELECT ? AS a 对应别名，其余占位符对应它之前最近的列名，
+// 如 a=?、a IN (?,?)、a BETWEEN ? AND ?
+func placeholderColumns(query string) []string {
+
+	var (
+		columns []string
+		last    string   // 最近的列名
+		group   []string // 当前括号内的列名列表
+		list    []string // 最近一个只包含列名的括号
+		plain   bool     // 当前括号内只有列名和逗号
+		values  bool     // 处于 VALUES 之后
+		idx     int      // VALUES 括号内的位置
+		depth   int
+		alias   = -1 // 最近的占位符，其后是 AS 别名时改为对应别名
+		as      bool
+	)
+
+	for i := 0; i < len(query); {
+
+		c := query[i]
+		if !isSpace(c) && !isIdent(c) && c != '"' && c != '`' {
+			alias, as = -1, false
+		}
+		switch {
+		case c == '\'': //跳过字符串常量
+			i++
+			for i < len(query) {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+
+		case c == '?' || (c == ':' || c == '$') && i+1 < len(query) && isDigit(query[i+1]):
+			i++
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+			plain = false
+			if values && len(list) > 0 {
+				columns = append(columns, list[idx%len(list)])
+				idx++
+			} else {
+				columns = append(columns, last)
+			}
+			alias = len(columns) - 1
+
+		case isIdent(c) || c == '"' || c == '`':
+			start := i
+			i++
+			for i < len(query) && (isIdent(query[i]) || isDigit(query[i]) || query[i] == '.' || query[i] == '"' || query[i] == '`') {
+				i++
+			}
+			word := strings.Trim(query[start:i], "\"`")
+			if dot := strings.LastIndex(word, "."); dot >= 0 {
+				word = strings.Trim(word[dot+1:], "\"`")
+			}
+
+			upper := strings.ToUpper(word)
+			if alias >= 0 && !as && upper == "AS" {
+				as = true
+				continue
+			}
+			if as {
+				columns[alias] = word
+			}
+			alias, as = -1, false
+
+			switch {
+			case upper == "VALUES":
+				values, idx = true, 0
+			case upper == "SELECT" || upper == "WHERE" || upper == "SET" || upper == "INTO":
+				values = false
+				plain = false
+			case sqlKeywords[upper]:
+				plain = false
+			default:
+				last = word
+				group = append(group, word)
+			}
+
+		case c == '(':
+			depth++
+			group, plain = nil, true
+			if values && depth == 1 {
+				idx = 0
+			}
+			i++
+
+		case c == ')':
+			if plain && len(group) > 0 {
+				list = group
+			}
+			depth--
+			group, plain = nil, false
+			i++
+
+		case c == ',' || isSpace(c):
+			i++
+
+		default:
+			plain = false
+			i++
+		}
+	}
+	return columns
+}
+
+func isIdent(c byte) bool {
+
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func isDigit(c byte) bool {
+
+	return c >= '0' && c <= '9'
+}
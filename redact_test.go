@@ -0,0 +1,64 @@
+package oram
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholderColumns(t *testing.T) {
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT * FROM t WHERE a=:1 AND b.card_no = :2", []string{"a", "card_no"}},
+		{"INSERT INTO t (name,card_no) values (:1,:2)", []string{"name", "card_no"}},
+		{"INSERT INTO t (a,b) VALUES (?,?),(?,?)", []string{"a", "b", "a", "b"}},
+		{"UPDATE t SET card_no=$1,name=$2 WHERE id=$3", []string{"card_no", "name", "id"}},
+		{"SELECT * FROM t WHERE id IN (?,?) AND x BETWEEN ? AND ?", []string{"id", "id", "x", "x"}},
+		{"SELECT * FROM t WHERE name='a=?' AND pin=?", []string{"pin"}},
+		{"SELECT * FROM t WHERE a=? OR NOT b=?", []string{"a", "b"}},
+		{"SELECT * FROM t WHERE `card_no`=? AND \"Pin\"=?", []string{"card_no", "Pin"}},
+		{"MERGE INTO t T USING (SELECT :1 AS card_no,:2 AS name FROM dual) S ON (T.card_no=S.card_no)", []string{"card_no", "name"}},
+		{"SELECT 1 FROM dual", nil},
+	}
+	for _, tt := range tests {
+
+		if got := placeholderColumns(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("placeholderColumns(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+type redactCard struct {
+	Id     int64  `db:"id"`
+	CardNo string `db:"redact_card_no" sensitive:"true"`
+}
+
+func TestRedactFirstStatement(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	var args []string
+	db.SetLogger(LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			if keyvals[i] == "args" {
+				args = append(args, keyvals[i+1].(string))
+			}
+		}
+	}))
+
+	//第一条语句执行前 Get 已注册 sensitive 列
+	var c redactCard
+	db.Where("redact_card_no=?", "6222020000000001").Get(&c)
+
+	if len(args) != 1 || strings.Contains(args[0], "6222") || !strings.Contains(args[0], redacted) {
+		t.Errorf("logged args = %q, want card number redacted", args)
+	}
+	if s := d.statements(); len(s) != 1 || !strings.Contains(s[0], "6222020000000001") {
+		t.Errorf("driver got %q, want the plain card number", s)
+	}
+}
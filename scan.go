@@ -0,0 +1,160 @@
+package oram
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found rows")
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ScanRows 把 QueryRows 返回的结果直接扫描到 out，out 为结构体切片指针时读取全部行，
+// 为结构体指针时读取第一行
+func ScanRows(rows *sql.Rows, out interface{}) error {
+
+	t := reflect.TypeOf(out)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return errors.New("ScanRows need a pointer")
+	}
+
+	if t.Elem().Kind() == reflect.Slice {
+		return rowsToList(rows, out)
+	}
+	return rowsToStruct(rows, out)
+}
+
+// scanner 按查询结果的列顺序把每行扫描到结构体字段，每次查询只计算一次列和字段的对应关系
+type scanner struct {
+	fields  []*fieldMeta
+	dest    []interface{}
+	values  []reflect.Value
+	convert []bool
+}
+
+func newScanner(rows *sql.Rows, t reflect.Type) (*scanner, error) {
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	model := getModel(t)
+	seen := make(map[*fieldMeta]bool, len(columns))
+	s := &scanner{
+		fields:  make([]*fieldMeta, len(columns)),
+		dest:    make([]interface{}, len(columns)),
+		values:  make([]reflect.Value, len(columns)),
+		convert: make([]bool, len(columns)),
+	}
+
+	for i, col := range columns {
+
+		f := model.column(col)
+		if f == nil || seen[f] {
+
+			s.dest[i] = new(interface{}) //没有对应字段的列，多表连接时同名的列只取第一个
+			continue
+		}
+		seen[f] = true
+		s.fields[i] = f
+
+		//已注册转换器和时间类型的字段先读取驱动原始值，再由 convertFrom 转换
+		if getConverter(f.typ) != nil || f.typ == timeType {
+
+			s.convert[i] = true
+			s.dest[i] = new(interface{})
+			continue
+		}
+		if f.scanner {
+			continue
+		}
+
+		//通过 *T 接收列值，NULL 时为 nil，写回字段时置为零值
+		s.values[i] = reflect.New(reflect.PtrTo(f.typ))
+		s.dest[i] = s.values[i].Interface()
+	}
+	return s, nil
+}
+
+// scan 把当前行写入结构体 v
+func (s *scanner) scan(rows *sql.Rows, v reflect.Value) error {
+
+	for i, f := range s.fields {
+
+		if f != nil && f.scanner && !s.convert[i] {
+			s.dest[i] = f.value(v).Addr().Interface()
+		}
+	}
+
+	if err := rows.Scan(s.dest...); err != nil {
+		return err
+	}
+
+	for i, f := range s.fields {
+
+		if f == nil || f.scanner && !s.convert[i] {
+			continue
+		}
+
+		field := f.value(v)
+		if s.convert[i] {
+
+			vl, err := convertFrom(f.typ, *(s.dest[i].(*interface{})))
+			if err != nil {
+				return fmt.Errorf("column %s: %v", f.column, err)
+			}
+			field.Set(vl)
+			continue
+		}
+
+		if ptr := s.values[i].Elem(); ptr.IsNil() {
+			field.Set(reflect.Zero(f.typ))
+		} else {
+			field.Set(ptr.Elem())
+		}
+	}
+	return nil
+}
+
+// scanValue 扫描只有一列的结果到非结构体类型的 v，NULL 时置为零值
+func scanValue(rows *sql.Rows, v reflect.Value) error {
+
+	if getConverter(v.Type()) != nil || v.Type() == timeType {
+
+		var src interface{}
+		if err := rows.Scan(&src); err != nil {
+			return err
+		}
+		vl, err := convertFrom(v.Type(), src)
+		if err != nil {
+			return err
+		}
+		v.Set(vl)
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr || reflect.PtrTo(v.Type()).Implements(scannerType) {
+		return rows.Scan(v.Addr().Interface())
+	}
+
+	ptr := reflect.New(reflect.PtrTo(v.Type()))
+	if err := rows.Scan(ptr.Interface()); err != nil {
+		return err
+	}
+
+	if ptr.Elem().IsNil() {
+		v.Set(reflect.Zero(v.Type()))
+	} else {
+		v.Set(ptr.Elem().Elem())
+	}
+	return nil
+}
+
+func isStruct(t reflect.Type) bool {
+
+	return t.Kind() == reflect.Struct && t != timeType
+}
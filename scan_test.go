@@ -0,0 +1,187 @@
+package oram
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type scanPerson struct {
+	Id     int64   `db:"id"`
+	Name   string  `db:"name"`
+	Score  float64 `db:"score"`
+	Active bool    `db:"active"`
+	Data   []byte  `db:"data"`
+	Level  int32   `db:"level"`
+}
+
+func scanRows() *fakeRows {
+
+	return &fakeRows{
+		cols: []string{"ID", "name", "score", "active", "data", "level", "extra"},
+		data: [][]driver.Value{
+			{int64(1), "a", 1.5, true, []byte("x"), "7", "ignored"},
+			{int64(2), []byte("b"), int64(2), int64(0), nil, int64(8), nil},
+		},
+	}
+}
+
+func TestTypedScan(t *testing.T) {
+
+	want := []scanPerson{
+		{Id: 1, Name: "a", Score: 1.5, Active: true, Data: []byte("x"), Level: 7},
+		{Id: 2, Name: "b", Score: 2, Active: false, Level: 8},
+	}
+
+	tests := []struct {
+		name string
+		run  func(db *ConDB) ([]scanPerson, error)
+	}{
+		{"Find []T", func(db *ConDB) ([]scanPerson, error) {
+			var list []scanPerson
+			return list, db.Table("tb_person").Find(&list).Err
+		}},
+		{"Find []*T", func(db *ConDB) ([]scanPerson, error) {
+			var list []*scanPerson
+			err := db.Table("tb_person").Find(&list).Err
+			out := make([]scanPerson, len(list))
+			for i, p := range list {
+				out[i] = *p
+			}
+			return out, err
+		}},
+		{"ScanRows", func(db *ConDB) ([]scanPerson, error) {
+			rows, err := db.QueryRows("select * from tb_person")
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+			var list []scanPerson
+			return list, ScanRows(rows, &list)
+		}},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(MySQL)
+		d.results = []*fakeRows{scanRows()}
+
+		got, err := tt.run(db)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, want)
+		}
+	}
+
+	//Get 读取第一行
+	db, d := newFakeDB(MySQL)
+	d.results = []*fakeRows{scanRows()}
+	var p scanPerson
+	if err := db.Table("tb_person").Get(&p); err != nil || !reflect.DeepEqual(p, want[0]) {
+		t.Errorf("Get = %+v, %v, want %+v", p, err, want[0])
+	}
+}
+
+func TestScanNoRows(t *testing.T) {
+
+	empty := func() []*fakeRows {
+		return []*fakeRows{{cols: []string{"id", "name"}}}
+	}
+
+	db, d := newFakeDB(MySQL)
+	d.results = empty()
+	var p scanPerson
+	if err := db.Table("tb_person").Get(&p); err != sql.ErrNoRows && err != ErrNotFound {
+		t.Errorf("Get without rows: err = %v", err)
+	}
+
+	d.results = empty()
+	var list []scanPerson
+	if err := db.Table("tb_person").Find(&list).Err; err != nil || len(list) != 0 {
+		t.Errorf("Find without rows = %v, %v", list, err)
+	}
+}
+
+// scanUpper 自定义 Scanner，NULL 时记为 "<null>"
+type scanUpper struct{ s string }
+
+func (u *scanUpper) Scan(src interface{}) error {
+
+	switch v := src.(type) {
+	case nil:
+		u.s = "<null>"
+	case string:
+		u.s = "upper:" + v
+	case []byte:
+		u.s = "upper:" + string(v)
+	}
+	return nil
+}
+
+type nullPerson struct {
+	Id    int64          `db:"id"`
+	Name  string         `db:"name"`
+	Age   *int           `db:"age"`
+	Phone sql.NullString `db:"phone"`
+	Nick  *string        `db:"nick"`
+	Code  scanUpper      `db:"code"`
+}
+
+func TestScanNull(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+	d.results = []*fakeRows{{
+		cols: []string{"id", "name", "age", "phone", "nick", "code"},
+		data: [][]driver.Value{
+			{int64(1), nil, nil, nil, nil, nil},
+			{int64(2), "b", int64(30), "138", "bb", "x"},
+		},
+	}}
+
+	var list []nullPerson
+	if err := db.Table("tb_person").Find(&list).Err; err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("%d rows, want 2", len(list))
+	}
+
+	p := list[0]
+	if p.Name != "" || p.Age != nil || p.Phone.Valid || p.Nick != nil || p.Code.s != "<null>" {
+		t.Errorf("NULL row = %+v", p)
+	}
+	p = list[1]
+	if p.Name != "b" || p.Age == nil || *p.Age != 30 || p.Phone != (sql.NullString{String: "138", Valid: true}) ||
+		p.Nick == nil || *p.Nick != "bb" || p.Code.s != "upper:x" {
+		t.Errorf("row = %+v", p)
+	}
+}
+
+func TestBindNull(t *testing.T) {
+
+	n, nick := 0, "n"
+	var nilNick *string
+
+	tests := []struct {
+		v    interface{}
+		want driver.Value
+	}{
+		{nilNick, nil},
+		{&nick, "n"},
+		{&n, int64(0)},
+		{sql.NullString{}, nil},
+		{sql.NullString{String: "138", Valid: true}, "138"},
+		{&sql.NullInt64{Int64: 5, Valid: true}, int64(5)},
+	}
+	for _, tt := range tests {
+
+		//与 database/sql 相同，Valuer 由其 Value 方法转换
+		got, err := driver.DefaultParameterConverter.ConvertValue(bindValue(tt.v))
+		if err != nil || got != tt.want {
+			t.Errorf("bindValue(%#v) = %#v, %v, want %#v", tt.v, got, err, tt.want)
+		}
+	}
+}
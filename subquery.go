@@ -0,0 +1,129 @@
+package oram
+
+import (
+	"bytes"
+	"strings"
+)
+
+// questionDialect 以 ? 作为占位符生成子查询，嵌入外层语句后与外层参数统一编号
+type questionDialect struct {
+	Dialect
+}
+
+func (questionDialect) Placeholder(i int) string { return "?" }
+
+// Build 返回查询语句和参数，占位符为 ?，使 ConDB 可以作为子查询用于 Where、Filter 和 From，如
+// db.Where("userid IN (?)", db.Table("tb_order").Select("userid").Where("amount>?", 100))
+func (m *ConDB) Build() (string, []interface{}) {
+
+	var q ConDB
+	if m.parent == nil {
+		q = *m.clone()
+	} else {
+		q = *m
+	}
+	q.dialect = questionDialect{m.getDialect()}
+	q.params, q.joinParams, q.Idx = nil, nil, 0
+
+	s := bytes.Buffer{}
+	s.WriteString("SELECT ")
+	s.WriteString(q.field)
+	s.WriteString(" FROM ")
+	s.WriteString(q.from())
+	s.WriteString(q.buildSql())
+
+	if q.group != "" {
+		s.WriteString(q.group)
+	}
+	if q.sort != "" {
+		s.WriteString(q.sort)
+	}
+	if q.Limit > 0 {
+		return q.getDialect().Paginate(s.String(), q.Offset, q.Limit-q.Offset), q.params
+	}
+	return s.String(), q.params
+}
+
+// From 以子查询 sub 作为查询的表，alias 为其别名，如
+// db.From(db.Table("tb_order").Select("userid,sum(amount) total").GroupBy("userid"), "t").Where("t.total>?", 1000)
+func (m *ConDB) From(sub *ConDB, alias string) *ConDB {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+
+	db.source = sub
+	db.alias = alias
+	return db
+}
+
+// hasTable 是否已指定查询的表，From 的子查询同样视为表
+func (db *ConDB) hasTable() bool {
+
+	return db.table != "" || db.source != nil
+}
+
+// Exists 生成 EXISTS (sub)
+func Exists(sub *ConDB) Cond {
+
+	return expr{"EXISTS ?", []interface{}{sub}}
+}
+
+// NotExists 生成 NOT EXISTS (sub)
+func NotExists(sub *ConDB) Cond {
+
+	return expr{"NOT EXISTS ?", []interface{}{sub}}
+}
+
+// expandSubqueries 把参数中的子查询展开到对应的 ? 位置，子查询的参数按位置并入参数列表。
+// ? 已经在括号中时不再加括号，如 "userid IN (?)"
+func expandSubqueries(query string, args []interface{}) (string, []interface{}) {
+
+	found := false
+	for _, a := range args {
+		if _, ok := a.(*ConDB); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return query, args
+	}
+
+	s := strings.Builder{}
+	out := make([]interface{}, 0, len(args))
+	k := 0
+	for i := 0; i < len(query); i++ {
+
+		c := query[i]
+		if c != '?' || k >= len(args) {
+
+			s.WriteByte(c)
+			continue
+		}
+
+		sub, ok := args[k].(*ConDB)
+		k++
+		if !ok {
+
+			s.WriteByte(c)
+			out = append(out, args[k-1])
+			continue
+		}
+
+		sql, subArgs := sub.Build()
+		before := strings.TrimRight(query[:i], " \t\r\n")
+		after := strings.TrimLeft(query[i+1:], " \t\r\n")
+		if strings.HasSuffix(before, "(") && strings.HasPrefix(after, ")") {
+			s.WriteString(sql)
+		} else {
+			s.WriteString("(" + sql + ")")
+		}
+		out = append(out, subArgs...)
+	}
+	return s.String(), append(out, args[k:]...)
+}
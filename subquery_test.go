@@ -0,0 +1,93 @@
+package oram
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubqueryPlaceholders(t *testing.T) {
+
+	db, _ := newFakeDB(Oracle)
+	sub := func() *ConDB {
+		return db.Table("tb_order").Select("userid").Where("amount>?", 100)
+	}
+
+	tests := []struct {
+		name  string
+		query func() (string, []interface{})
+		want  string
+		args  []interface{}
+	}{
+		{
+			"in list",
+			func() (string, []interface{}) {
+				q := db.Table("t").Where("x=?", 1).Where("userid IN (?)", sub()).Where("y=?", 2)
+				return q.buildSql(), q.params
+			},
+			" WHERE x=:1 AND userid IN (SELECT userid FROM tb_order WHERE amount>:2) AND y=:3",
+			[]interface{}{1, 100, 2},
+		},
+		{
+			"in cond",
+			func() (string, []interface{}) {
+				q := db.Table("t").Filter(Eq("x", 1), In("userid", sub()))
+				return q.buildSql(), q.params
+			},
+			" WHERE x=:1 AND userid IN (SELECT userid FROM tb_order WHERE amount>:2)",
+			[]interface{}{1, 100},
+		},
+		{
+			"exists",
+			func() (string, []interface{}) {
+				q := db.Table("t").Filter(Exists(db.Table("o").Where("o.u=t.id AND o.k=?", 3)), Eq("z", 4))
+				return q.buildSql(), q.params
+			},
+			" WHERE EXISTS (SELECT * FROM o WHERE o.u=t.id AND o.k=:1) AND z=:2",
+			[]interface{}{3, 4},
+		},
+		{
+			"from",
+			func() (string, []interface{}) {
+				q := db.From(db.Table("o").Select("u,sum(a) s").Where("k=?", 9).GroupBy("u"), "t").Where("t.s>?", 1000)
+				return q.from() + q.buildSql(), q.params
+			},
+			"(SELECT u,sum(a) s FROM o WHERE k=:1 group by u) t WHERE t.s>:2",
+			[]interface{}{9, 1000},
+		},
+		{
+			"build",
+			func() (string, []interface{}) {
+				return db.From(db.Table("o").Where("k=?", 9), "t").Where("t.s>?", 1000).Build()
+			},
+			"SELECT * FROM (SELECT * FROM o WHERE k=?) t WHERE t.s>?",
+			[]interface{}{9, 1000},
+		},
+	}
+	for _, tt := range tests {
+
+		query, args := tt.query()
+		if query != tt.want {
+			t.Errorf("%s: query = %q, want %q", tt.name, query, tt.want)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: args = %v, want %v", tt.name, args, tt.args)
+		}
+	}
+}
+
+func TestFindAllFrom(t *testing.T) {
+
+	db, d := newFakeDB(Oracle)
+	d.results = []*fakeRows{{cols: []string{"id", "name"}}}
+
+	var list []ctxPerson
+	err := db.From(db.Table("tb_person").Where("status=?", 1), "p").FindAll(&list).Err
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"SELECT * FROM (SELECT * FROM tb_person WHERE status=:1) p [1]"}
+	if !reflect.DeepEqual(d.statements(), want) {
+		t.Errorf("statements = %q, want %q", d.statements(), want)
+	}
+}
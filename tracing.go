@@ -0,0 +1,197 @@
+package oram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// span 属性名，与 OpenTelemetry 数据库语义约定一致
+const (
+	AttrDBSystem     = "db.system"
+	AttrDBStatement  = "db.statement"
+	AttrDBOperation  = "db.operation"
+	AttrDBTable      = "db.sql.table"
+	AttrRowsAffected = "db.rows_affected"
+)
+
+// Tracer 创建 span，ctx 中的 span 为父 span，用于接入 OpenTelemetry 等链路追踪
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span 一次数据库调用
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
+
+// SetTracer 为每条语句、事务以及提交、回滚创建 span，同一个初始化的 ConDB 复制出的 ConDB 和事务共用，
+// t 为 nil 时关闭
+func (m *ConDB) SetTracer(t Tracer) {
+
+	c := m.config()
+	c.mu.Lock()
+	c.tracer = t
+	c.mu.Unlock()
+}
+
+func (m *ConDB) getTracer() Tracer {
+
+	if m.conf == nil {
+		return nil
+	}
+
+	m.conf.mu.RLock()
+	defer m.conf.mu.RUnlock()
+
+	return m.conf.tracer
+}
+
+// startSpan 以 ctx 中的 span 为父 span 创建 span，没有设置 Tracer 时返回不做记录的 span
+func (m *ConDB) startSpan(ctx context.Context, name string) (context.Context, Span) {
+
+	tracer := m.getTracer()
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	ctx, span := tracer.Start(ctx, name)
+	span.SetAttribute(AttrDBSystem, dbSystem(m.getDialect().Name()))
+	return ctx, span
+}
+
+// dbSystem 返回方言对应的 OpenTelemetry db.system 取值，未知的方言使用其名称
+func dbSystem(dialect string) string {
+
+	switch dialect {
+	case "postgres":
+		return "postgresql"
+	default:
+		return dialect
+	}
+}
+
+// endSpan 记录错误并结束 span
+func endSpan(span Span, err error) {
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// traceTx 以 span 记录事务的提交或回滚
+func (m *ConDB) traceTx(name string, fn func() error) error {
+
+	_, span := m.startSpan(m.context(), name)
+	err := fn()
+	endSpan(span, err)
+	return err
+}
+
+// spanName 返回语句的 span 名称，如 select tb_person
+func spanName(stmt *Statement) string {
+
+	if stmt.Table == "" {
+		return stmt.Op
+	}
+	return stmt.Op + " " + stmt.Table
+}
+
+// Recorder 在内存中记录 span 的 Tracer，用于测试
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan Recorder 记录的 span
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Err        error
+	Start      time.Time
+	End        time.Time
+
+	rec *Recorder
+}
+
+type spanKey struct{}
+
+func NewRecorder() *Recorder {
+
+	return &Recorder{}
+}
+
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+
+	parent, _ := ctx.Value(spanKey{}).(*RecordedSpan)
+	s := &recordedSpan{&RecordedSpan{
+		Name:       name,
+		Parent:     parent,
+		Attributes: make(map[string]interface{}),
+		Start:      time.Now(),
+		rec:        r,
+	}}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, s.RecordedSpan)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, s.RecordedSpan), s
+}
+
+// Spans 按开始顺序返回记录的 span
+func (r *Recorder) Spans() []*RecordedSpan {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := make([]*RecordedSpan, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Reset 清空记录的 span
+func (r *Recorder) Reset() {
+
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+}
+
+// recordedSpan 实现 Span，RecordedSpan 的字段名与 Span 的方法名相同，不能直接实现
+type recordedSpan struct {
+	*RecordedSpan
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+
+	s.rec.mu.Lock()
+	s.Attributes[key] = value
+	s.rec.mu.Unlock()
+}
+
+func (s *recordedSpan) RecordError(err error) {
+
+	s.rec.mu.Lock()
+	s.Err = err
+	s.rec.mu.Unlock()
+}
+
+func (s *recordedSpan) End() {
+
+	s.rec.mu.Lock()
+	s.RecordedSpan.End = time.Now()
+	s.rec.mu.Unlock()
+}
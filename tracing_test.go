@@ -0,0 +1,102 @@
+package oram
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+
+	db, d := newFakeDB(PostgreSQL)
+	rec := NewRecorder()
+	db.SetTracer(rec)
+
+	err := db.TransactionContext(context.Background(), PropagationRequired, func(ctx context.Context) error {
+
+		db.WithContext(ctx).Table("tb_person").Where("id=?", 1).Count()
+		d.failOn = "DELETE"
+		db.WithContext(ctx).Table("tb_person").Where("id=?", 1).Delete()
+		d.failOn = ""
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := rec.Spans()
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+	}
+	want := []string{"transaction", "select tb_person", "delete tb_person", "commit"}
+	if len(names) != len(want) {
+		t.Fatalf("spans = %q, want %q", names, want)
+	}
+
+	tests := []struct {
+		name   string
+		parent string
+		attrs  map[string]interface{}
+		err    bool
+	}{
+		{"transaction", "", map[string]interface{}{AttrDBSystem: "postgresql"}, false},
+		{"select tb_person", "transaction", map[string]interface{}{
+			AttrDBSystem:    "postgresql",
+			AttrDBStatement: "SELECT count(*) FROM tb_person WHERE id=$1",
+			AttrDBOperation: OpSelect,
+			AttrDBTable:     "tb_person",
+		}, false},
+		{"delete tb_person", "transaction", map[string]interface{}{AttrDBOperation: OpDelete}, true},
+		{"commit", "transaction", nil, false},
+	}
+	for i, tt := range tests {
+
+		s := spans[i]
+		if s.Name != tt.name {
+			t.Errorf("span %d = %q, want %q", i, s.Name, tt.name)
+		}
+		if parent := ""; s.Parent != nil {
+			parent = s.Parent.Name
+			if parent != tt.parent {
+				t.Errorf("%s parent = %q, want %q", s.Name, parent, tt.parent)
+			}
+		} else if tt.parent != "" {
+			t.Errorf("%s has no parent, want %q", s.Name, tt.parent)
+		}
+		for k, v := range tt.attrs {
+			if s.Attributes[k] != v {
+				t.Errorf("%s %s = %v, want %v", s.Name, k, s.Attributes[k], v)
+			}
+		}
+		if (s.Err != nil) != tt.err {
+			t.Errorf("%s err = %v, want error %v", s.Name, s.Err, tt.err)
+		}
+		if s.End.IsZero() {
+			t.Errorf("%s not ended", s.Name)
+		}
+	}
+
+	rec.Reset()
+	if n := len(rec.Spans()); n != 0 {
+		t.Errorf("%d spans after Reset", n)
+	}
+}
+
+func TestDbSystem(t *testing.T) {
+
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Oracle, "oracle"},
+		{MySQL, "mysql"},
+		{PostgreSQL, "postgresql"},
+		{SQLite, "sqlite"},
+	}
+	for _, tt := range tests {
+
+		if got := dbSystem(tt.dialect.Name()); got != tt.want {
+			t.Errorf("dbSystem(%q) = %q, want %q", tt.dialect.Name(), got, tt.want)
+		}
+	}
+}
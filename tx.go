@@ -0,0 +1,269 @@
+package oram
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// txState 同一个事务共享的状态，嵌套事务的每个保存点有自己的 txState
+type txState struct {
+	parent     *txState
+	savepoints int
+	err        error //TxBegin 开启事务的错误
+	onCommit   []func()
+	onRollback []func()
+}
+
+func (s *txState) root() *txState {
+
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// beginErr 返回 TxBegin 开启事务失败的错误，此时语句不能在事务外以自动提交执行
+func (m *ConDB) beginErr() error {
+
+	if m.tx != nil || m.txs == nil {
+		return nil
+	}
+	return m.txs.root().err
+}
+
+// commit 事务提交后执行 OnCommit 注册的回调
+func (s *txState) commit() {
+
+	for _, fn := range s.onCommit {
+		fn()
+	}
+	s.onCommit, s.onRollback = nil, nil
+}
+
+// rollback 事务或保存点回滚后执行 OnRollback 注册的回调，OnCommit 的回调不再执行
+func (s *txState) rollback() {
+
+	for _, fn := range s.onRollback {
+		fn()
+	}
+	s.onCommit, s.onRollback = nil, nil
+}
+
+// release 保存点成功结束，回调交给外层事务，在外层提交或回滚时执行
+func (s *txState) release() {
+
+	s.parent.onCommit = append(s.parent.onCommit, s.onCommit...)
+	s.parent.onRollback = append(s.parent.onRollback, s.onRollback...)
+	s.onCommit, s.onRollback = nil, nil
+}
+
+// OnCommit 注册事务提交后执行的回调，在保存点中注册时保存点回滚则不执行。
+// 没有绑定事务时立即执行
+func (m *ConDB) OnCommit(fn func()) {
+
+	if m.txs == nil {
+
+		fn()
+		return
+	}
+	m.txs.onCommit = append(m.txs.onCommit, fn)
+}
+
+// OnRollback 注册事务回滚后执行的回调，在保存点中注册时保存点回滚后即执行。
+// 没有绑定事务时不执行
+func (m *ConDB) OnRollback(fn func()) {
+
+	if m.txs == nil {
+		return
+	}
+	m.txs.onRollback = append(m.txs.onRollback, fn)
+}
+
+// Propagation 事务传播方式，用于 TransactionContext
+type Propagation int
+
+const (
+	// PropagationRequired 加入 context 中已有的事务，没有时开启新事务
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew 总是开启新的独立事务，与 context 中已有的事务互不影响
+	PropagationRequiresNew
+	// PropagationNested 在 context 中已有的事务里使用保存点，没有时开启新事务
+	PropagationNested
+)
+
+type txKey struct{}
+
+// txFromContext 返回 ctx 中与 m 使用同一个数据库的事务
+func (m *ConDB) txFromContext(ctx context.Context) *ConDB {
+
+	if ctx == nil {
+		return nil
+	}
+	if tx, ok := ctx.Value(txKey{}).(*ConDB); ok && tx.Db == m.Db {
+		return tx
+	}
+	return nil
+}
+
+// TransactionContext 按传播方式 p 在事务中执行 fn，fn 收到的 ctx 携带该事务，
+// 通过 WithContext(ctx) 执行的操作自动加入该事务，无需传递 *ConDB
+func (m *ConDB) TransactionContext(ctx context.Context, p Propagation, fn func(ctx context.Context) error) error {
+
+	run := func(tx *ConDB) error {
+		return fn(tx.ctx)
+	}
+
+	cur := m.txFromContext(ctx)
+	if cur != nil && p == PropagationRequired {
+		return fn(ctx)
+	}
+	if cur != nil && p == PropagationNested {
+		return cur.nested(ctx, run)
+	}
+	return m.begin(ctx, nil, run)
+}
+
+// Transaction 在事务中执行 fn，fn 返回 nil 时提交，返回错误或 panic 时回滚，
+// panic 在回滚后继续抛出。fn 中通过 tx 执行的操作都在该事务中。
+// 在已绑定事务的 ConDB 上调用时使用保存点，出错只回滚到该保存点
+func (m *ConDB) Transaction(fn func(tx *ConDB) error) error {
+
+	return m.TransactionOpts(m.context(), nil, fn)
+}
+
+// TransactionOpts 与 Transaction 相同，按 opts 指定的隔离级别、只读属性开启事务，
+// ctx 取消或超时时事务自动回滚。在已绑定事务的 ConDB 上调用时 opts 不生效
+func (m *ConDB) TransactionOpts(ctx context.Context, opts *sql.TxOptions, fn func(tx *ConDB) error) (err error) {
+
+	if m.tx != nil {
+		return m.nested(ctx, fn)
+	}
+	return m.begin(ctx, opts, fn)
+}
+
+// begin 开启新事务执行 fn
+func (m *ConDB) begin(ctx context.Context, opts *sql.TxOptions, fn func(tx *ConDB) error) (err error) {
+
+	ctx, span := m.startSpan(ctx, "transaction")
+	defer func() { endSpan(span, err) }()
+
+	tx, err := m.Db.BeginTx(ctx, opts)
+	if err != nil {
+
+		m.trace("begin error:", err)
+		return err
+	}
+
+	txs := &txState{}
+	db := m.session(tx, txs)
+	db.ctx = context.WithValue(ctx, txKey{}, db)
+
+	defer func() {
+
+		if p := recover(); p != nil {
+
+			if rerr := db.traceTx("rollback", tx.Rollback); rerr != nil {
+				db.trace("rollback error:", rerr)
+			}
+			txs.rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(db); err != nil {
+
+		rerr := db.traceTx("rollback", tx.Rollback)
+		txs.rollback()
+		if rerr != nil {
+
+			db.trace("rollback error:", rerr)
+			return fmt.Errorf("%w (rollback: %v)", err, rerr)
+		}
+		return err
+	}
+
+	if err = db.traceTx("commit", tx.Commit); err != nil {
+
+		db.trace("commit error:", err)
+		txs.rollback()
+		return err
+	}
+	txs.commit()
+	return nil
+}
+
+// nested 在当前事务中以保存点执行 fn
+func (m *ConDB) nested(ctx context.Context, fn func(tx *ConDB) error) (err error) {
+
+	if m.txs == nil {
+		m.txs = &txState{}
+	}
+	root := m.txs.root()
+	root.savepoints++
+	name := fmt.Sprintf("oram_sp_%d", root.savepoints)
+
+	if err = m.Savepoint(name); err != nil {
+		return err
+	}
+
+	txs := &txState{parent: m.txs}
+	db := m.session(m.tx, txs)
+	db.ctx = context.WithValue(ctx, txKey{}, db) //WithContext(ctx) 加入保存点，回调注册到保存点
+
+	defer func() {
+
+		if p := recover(); p != nil {
+
+			if rerr := m.RollbackTo(name); rerr != nil {
+				db.trace("rollback error:", rerr)
+			}
+			txs.rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(db); err != nil {
+
+		rerr := m.RollbackTo(name)
+		txs.rollback()
+		if rerr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rerr)
+		}
+		return err
+	}
+	txs.release()
+	return nil
+}
+
+// Savepoint 在当前事务中创建保存点
+func (m *ConDB) Savepoint(name string) error {
+
+	if m.tx == nil {
+		return m.noTx()
+	}
+
+	sql := "SAVEPOINT " + name
+
+	_, err := m.execContext(sql)
+	return err
+}
+
+// RollbackTo 回滚到保存点 name，保存点之前的操作不受影响
+func (m *ConDB) RollbackTo(name string) error {
+
+	if m.tx == nil {
+		return m.noTx()
+	}
+
+	sql := "ROLLBACK TO SAVEPOINT " + name
+
+	_, err := m.execContext(sql)
+	return err
+}
+
+// session 返回绑定事务 tx 的 ConDB，用法与初始化的 ConDB 相同，每次查询从它复制新的 ConDB
+func (m *ConDB) session(tx *sql.Tx, txs *txState) *ConDB {
+
+	return &ConDB{Db: m.Db, dialect: m.dialect, ctx: m.ctx, conf: m.conf, tx: tx, txs: txs}
+}
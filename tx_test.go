@@ -0,0 +1,344 @@
+package oram
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransaction(t *testing.T) {
+
+	errFail := errors.New("fail")
+
+	tests := []struct {
+		name  string
+		fn    func(tx *ConDB) error
+		err   error
+		panic bool
+		want  []string
+	}{
+		{"commit", func(tx *ConDB) error {
+			return tx.Table("tb_person").Where("id=?", 1).Delete()
+		}, nil, false, []string{"BEGIN", "DELETE  FROM tb_person WHERE id=? [1]", "COMMIT"}},
+		{"rollback on error", func(tx *ConDB) error {
+			tx.Table("tb_person").Where("id=?", 1).Delete()
+			return errFail
+		}, errFail, false, []string{"BEGIN", "DELETE  FROM tb_person WHERE id=? [1]", "ROLLBACK"}},
+		{"rollback on panic", func(tx *ConDB) error {
+			panic("boom")
+		}, nil, true, []string{"BEGIN", "ROLLBACK"}},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(MySQL)
+
+		var err error
+		recovered := func() (p interface{}) {
+			defer func() { p = recover() }()
+			err = db.Transaction(tt.fn)
+			return nil
+		}()
+
+		if (recovered != nil) != tt.panic {
+			t.Errorf("%s: panic = %v", tt.name, recovered)
+		}
+		if err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if got := d.statements(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: statements = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNestedTransaction(t *testing.T) {
+
+	errInner := errors.New("inner")
+
+	tests := []struct {
+		name  string
+		inner []error
+		want  []string
+	}{
+		{"release", []error{nil}, []string{
+			"BEGIN",
+			"SAVEPOINT oram_sp_1 []",
+			"DELETE  FROM tb_order WHERE id=? [1]",
+			"COMMIT",
+		}},
+		{"rollback to savepoint", []error{errInner, nil}, []string{
+			"BEGIN",
+			"SAVEPOINT oram_sp_1 []",
+			"DELETE  FROM tb_order WHERE id=? [1]",
+			"ROLLBACK TO SAVEPOINT oram_sp_1 []",
+			"SAVEPOINT oram_sp_2 []",
+			"DELETE  FROM tb_order WHERE id=? [2]",
+			"COMMIT",
+		}},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(MySQL)
+
+		err := db.Transaction(func(tx *ConDB) error {
+
+			for i, inner := range tt.inner {
+
+				id := i + 1
+				ierr := tx.Transaction(func(sp *ConDB) error {
+					sp.Table("tb_order").Where("id=?", id).Delete()
+					return inner
+				})
+				if ierr != inner {
+					t.Errorf("%s: savepoint %d err = %v, want %v", tt.name, id, ierr, inner)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		if got := d.statements(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: statements = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTxBeginOpts(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	err := db.TransactionOpts(context.Background(), opts, func(tx *ConDB) error {
+		tx.Table("tb_person").Count()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := db.TxBeginOpts(context.Background(), &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"BEGIN Serializable READ ONLY",
+		"SELECT count(*) FROM tb_person []",
+		"COMMIT",
+		"BEGIN Read Committed",
+		"COMMIT",
+	}
+	if got := d.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func TestTxBeginFailed(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+	d.failOn = "BEGIN"
+
+	tx := db.TxBegin()
+	if tx.Err == nil {
+		t.Fatal("TxBegin succeeded")
+	}
+	begin := tx.Err
+
+	called := false
+	tx.OnCommit(func() { called = true })
+
+	//开启事务失败后语句不能以自动提交执行
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"Delete", tx.Table("tb_person").Where("id=?", 1).Delete()},
+		{"Insert", tx.Table("tb_person").Insert(&ctxPerson{Name: "a"})},
+		{"QueryRow", tx.QueryRow("select 1").Scan(new(int))},
+		{"Commit", tx.Commit()},
+		{"Rollback", tx.Rollback()},
+	}
+	for _, tt := range tests {
+
+		if tt.err != begin {
+			t.Errorf("%s: err = %v, want %v", tt.name, tt.err, begin)
+		}
+	}
+	if called {
+		t.Error("OnCommit ran without a transaction")
+	}
+	if s := d.statements(); len(s) != 0 {
+		t.Errorf("statements = %q, want none", s)
+	}
+}
+
+func TestTxContextTimeout(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx := db.TxBeginOpts(ctx, nil)
+	if tx.Err != nil {
+		t.Fatal(tx.Err)
+	}
+	cancel()
+
+	//database/sql 在 ctx 取消后自动回滚
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+
+		if s := d.statements(); len(s) == 2 && s[1] == "ROLLBACK" {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("statements = %q, want BEGIN, ROLLBACK", d.statements())
+}
+
+func TestTransactionContext(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		inner Propagation
+		err   error
+		want  []string
+	}{
+		{"required joins", PropagationRequired, nil, []string{
+			"BEGIN",
+			"DELETE  FROM tb_person WHERE id=? [1]",
+			"DELETE  FROM tb_order WHERE id=? [2]",
+			"COMMIT",
+		}},
+		{"nested uses savepoint", PropagationNested, errors.New("inner"), []string{
+			"BEGIN",
+			"DELETE  FROM tb_person WHERE id=? [1]",
+			"SAVEPOINT oram_sp_1 []",
+			"DELETE  FROM tb_order WHERE id=? [2]",
+			"ROLLBACK TO SAVEPOINT oram_sp_1 []",
+			"COMMIT",
+		}},
+		{"requires new", PropagationRequiresNew, nil, []string{
+			"BEGIN",
+			"DELETE  FROM tb_person WHERE id=? [1]",
+			"BEGIN",
+			"DELETE  FROM tb_order WHERE id=? [2]",
+			"COMMIT",
+			"COMMIT",
+		}},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(MySQL)
+		db.Db.SetMaxOpenConns(2) //RequiresNew 使用另一个连接
+
+		err := db.TransactionContext(context.Background(), PropagationRequired, func(ctx context.Context) error {
+
+			//只传递 ctx，WithContext 加入 ctx 中的事务
+			db.WithContext(ctx).Table("tb_person").Where("id=?", 1).Delete()
+
+			ierr := db.TransactionContext(ctx, tt.inner, func(ctx context.Context) error {
+				db.WithContext(ctx).Table("tb_order").Where("id=?", 2).Delete()
+				return tt.err
+			})
+			if ierr != tt.err {
+				t.Errorf("%s: inner err = %v, want %v", tt.name, ierr, tt.err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if got := d.statements(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: statements = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTransactionContextOtherDB(t *testing.T) {
+
+	db, d := newFakeDB(MySQL)
+	other, od := newFakeDB(MySQL)
+
+	db.TransactionContext(context.Background(), PropagationRequired, func(ctx context.Context) error {
+		return other.WithContext(ctx).Table("tb_person").Where("id=?", 1).Delete()
+	})
+
+	if s := d.statements(); !reflect.DeepEqual(s, []string{"BEGIN", "COMMIT"}) {
+		t.Errorf("db statements = %q", s)
+	}
+	if s := od.statements(); !reflect.DeepEqual(s, []string{"DELETE  FROM tb_person WHERE id=? [1]"}) {
+		t.Errorf("other statements = %q, want the delete outside the transaction", s)
+	}
+}
+
+func TestTxCallbacks(t *testing.T) {
+
+	errInner := errors.New("inner")
+
+	tests := []struct {
+		name  string
+		outer error
+		run   func(db *ConDB, ctx context.Context, log func(string) func())
+		want  []string
+	}{
+		{"commit", nil, func(db *ConDB, ctx context.Context, log func(string) func()) {
+			tx := db.WithContext(ctx)
+			tx.OnCommit(log("commit-1"))
+			tx.OnRollback(log("rollback"))
+			tx.OnCommit(log("commit-2"))
+		}, []string{"commit-1", "commit-2"}},
+		{"rollback", errors.New("outer"), func(db *ConDB, ctx context.Context, log func(string) func()) {
+			tx := db.WithContext(ctx)
+			tx.OnCommit(log("commit"))
+			tx.OnRollback(log("rollback"))
+		}, []string{"rollback"}},
+		{"savepoint rolled back", nil, func(db *ConDB, ctx context.Context, log func(string) func()) {
+			db.WithContext(ctx).OnCommit(log("outer-commit"))
+			db.TransactionContext(ctx, PropagationNested, func(ctx context.Context) error {
+				tx := db.WithContext(ctx)
+				tx.OnCommit(log("inner-commit"))
+				tx.OnRollback(log("inner-rollback"))
+				return errInner
+			})
+			log("after-savepoint")()
+		}, []string{"inner-rollback", "after-savepoint", "outer-commit"}},
+		{"savepoint released", errors.New("outer"), func(db *ConDB, ctx context.Context, log func(string) func()) {
+			db.WithContext(ctx).Transaction(func(sp *ConDB) error {
+				sp.OnCommit(log("inner-commit"))
+				sp.OnRollback(log("inner-rollback"))
+				return nil
+			})
+			log("after-savepoint")()
+		}, []string{"after-savepoint", "inner-rollback"}},
+	}
+	for _, tt := range tests {
+
+		db, _ := newFakeDB(MySQL)
+
+		var got []string
+		log := func(s string) func() {
+			return func() { got = append(got, s) }
+		}
+		db.TransactionContext(context.Background(), PropagationRequired, func(ctx context.Context) error {
+			tt.run(db, ctx, log)
+			return tt.outer
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: callbacks = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	//没有事务时 OnCommit 立即执行，OnRollback 不执行
+	db, _ := newFakeDB(MySQL)
+	var got []string
+	db.OnRollback(func() { got = append(got, "rollback") })
+	db.OnCommit(func() { got = append(got, "commit") })
+	if !reflect.DeepEqual(got, []string{"commit"}) {
+		t.Errorf("without transaction: callbacks = %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+package oram
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Upsert 按 conflict 字段插入或更新一行，Oracle 使用 MERGE INTO 实现。
+// conflict 为空时按主键 id 匹配，主键为空时不会匹配已有的行，等同于 Insert；
+// 匹配时默认更新除 conflict 外的全部字段，可以通过 Select("a,b") 指定只更新部分字段。
+// 执行前依次调用 PreInsert、BeforeInsert、PreUpdate、BeforeUpdate，任一返回错误时中止
+func (m *ConDB) Upsert(i interface{}, conflict ...string) error {
+
+	var db *ConDB
+	if m.parent == nil {
+
+		db = m.clone()
+	} else {
+		db = m
+	}
+	registerModel(i)
+
+	if db.table == "" {
+		db.table = getTable(i)
+	}
+
+	d := db.getDialect()
+	data := toMap(reflect.ValueOf(i), reflect.TypeOf(i))
+
+	idKey := getModel(reflect.TypeOf(i)).keyColumn()
+	if len(conflict) == 0 {
+		conflict = []string{idKey}
+	}
+	if id, ok := data[idKey]; ok && isZero(bindValue(id)) && contains(conflict, idKey) {
+		return db.Insert(i)
+	}
+
+	legacyHook(i, "PreInsert")
+	if err := db.beforeInsert(i); err != nil {
+		return err
+	}
+	legacyHook(i, "PreUpdate")
+	if err := db.beforeUpdate(i); err != nil {
+		return err
+	}
+	data = toMap(reflect.ValueOf(i), reflect.TypeOf(i))
+
+	//主键为空时由数据库生成，Oracle 在 MERGE 的插入分支取序列值，匹配更新时不消耗序列
+	auto := false
+	if id, ok := data[idKey]; ok && isZero(bindValue(id)) {
+
+		delete(data, idKey)
+		auto = true
+	}
+
+	columns := make([]string, 0, len(data))
+	for k := range data {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	for _, col := range conflict {
+
+		if _, ok := data[col]; !ok {
+
+			db.trace("Upsert conflict column not found:", col)
+			return errors.New("Upsert conflict column not found: " + col)
+		}
+	}
+
+	update := make([]string, 0, len(columns))
+	if db.field != "" && db.field != "*" {
+
+		for _, col := range strings.Split(db.field, ",") {
+
+			col = strings.TrimSpace(col)
+			if col != "" && !contains(conflict, col) {
+				update = append(update, col)
+			}
+		}
+	} else {
+
+		for _, col := range columns {
+
+			if col != idKey && !contains(conflict, col) {
+				update = append(update, col)
+			}
+		}
+	}
+
+	args := make([]interface{}, len(columns))
+	for x, col := range columns {
+		args[x] = bindValue(data[col])
+	}
+
+	seqKey := ""
+	if auto && d.Sequence(db.table) != "" {
+		seqKey = idKey
+	}
+	sql := d.Upsert(db.table, seqKey, columns, conflict, update)
+
+	db.Result, db.Err = db.execContext(sql, args...)
+	if db.Err != nil {
+
+		return db.Err
+	}
+
+	if auto {
+		return db.upsertId(reflect.ValueOf(i), conflict, data)
+	}
+	return nil
+}
+
+// upsertId 按 conflict 字段查出插入或更新的行的主键，写回结构体和 LastInsertId
+func (db *ConDB) upsertId(v reflect.Value, conflict []string, data map[string]interface{}) error {
+
+	key := getModel(v.Type()).key
+	if key == nil {
+		return nil
+	}
+	switch key.typ.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+	default:
+		return nil
+	}
+
+	d := db.getDialect()
+	s := bytes.Buffer{}
+	s.WriteString("SELECT ")
+	s.WriteString(key.column)
+	s.WriteString(" FROM ")
+	s.WriteString(db.table)
+	args := make([]interface{}, len(conflict))
+	for x, col := range conflict {
+
+		if x == 0 {
+			s.WriteString(" WHERE ")
+		} else {
+			s.WriteString(" AND ")
+		}
+		s.WriteString(col)
+		s.WriteString("=")
+		s.WriteString(d.Placeholder(x + 1))
+		args[x] = bindValue(data[col])
+	}
+
+	if db.Err = db.queryRowContext(s.String(), args...).Scan(&db.LastInsertId); db.Err != nil {
+
+		return db.Err
+	}
+	setId(v, db.LastInsertId)
+	return nil
+}
+
+func contains(list []string, s string) bool {
+
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package oram
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type upsertPerson struct {
+	Id     int64  `db:"id"`
+	Userid string `db:"userid"`
+	Name   string `db:"name"`
+	Phone  string `db:"phone"`
+
+	hooks []string
+	veto  string
+}
+
+func (p *upsertPerson) PreInsert() { p.hooks = append(p.hooks, "PreInsert") }
+
+func (p *upsertPerson) PreUpdate() { p.hooks = append(p.hooks, "PreUpdate") }
+
+func (p *upsertPerson) BeforeInsert(db *ConDB) error {
+
+	p.hooks = append(p.hooks, "BeforeInsert")
+	if p.veto == "insert" {
+		return errors.New("invalid insert")
+	}
+	return nil
+}
+
+func (p *upsertPerson) BeforeUpdate(db *ConDB) error {
+
+	p.hooks = append(p.hooks, "BeforeUpdate")
+	if p.veto == "update" {
+		return errors.New("invalid update")
+	}
+	return nil
+}
+
+func TestUpsert(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		p        upsertPerson
+		fields   string
+		conflict []string
+		want     []string
+		id       int64
+		hooks    string
+		err      string
+	}{
+		{
+			name:    "zero id inserts",
+			dialect: Oracle,
+			want: []string{
+				"select seq_tb_person.nextval from dual []",
+				"INSERT INTO  tb_person",
+			},
+			id:    1,
+			hooks: "PreInsert,BeforeInsert",
+		},
+		{
+			name:    "match by id",
+			dialect: Oracle,
+			p:       upsertPerson{Id: 3, Userid: "u1", Name: "a", Phone: "p"},
+			want: []string{
+				"MERGE INTO tb_person T USING (SELECT :1 AS id,:2 AS name,:3 AS phone,:4 AS userid FROM dual) S ON (T.id=S.id) " +
+					"WHEN MATCHED THEN UPDATE SET T.name=S.name,T.phone=S.phone,T.userid=S.userid " +
+					"WHEN NOT MATCHED THEN INSERT (id,name,phone,userid) VALUES (S.id,S.name,S.phone,S.userid) [3 a p u1]",
+			},
+			id:    3,
+			hooks: "PreInsert,BeforeInsert,PreUpdate,BeforeUpdate",
+		},
+		{
+			name:     "sequence in merge",
+			dialect:  Oracle,
+			p:        upsertPerson{Userid: "u1", Name: "a"},
+			fields:   "name",
+			conflict: []string{"userid"},
+			want: []string{
+				"MERGE INTO tb_person T USING (SELECT :1 AS name,:2 AS phone,:3 AS userid FROM dual) S ON (T.userid=S.userid) " +
+					"WHEN MATCHED THEN UPDATE SET T.name=S.name " +
+					"WHEN NOT MATCHED THEN INSERT (name,phone,userid,id) VALUES (S.name,S.phone,S.userid,seq_tb_person.nextval) [a  u1]",
+				"SELECT id FROM tb_person WHERE userid=:1 [u1]",
+			},
+			id:    1,
+			hooks: "PreInsert,BeforeInsert,PreUpdate,BeforeUpdate",
+		},
+		{
+			name:     "mysql",
+			dialect:  MySQL,
+			p:        upsertPerson{Userid: "u1", Name: "a"},
+			conflict: []string{"userid"},
+			want: []string{
+				"INSERT INTO tb_person (name,phone,userid) VALUES (?,?,?) ON DUPLICATE KEY UPDATE name=VALUES(name),phone=VALUES(phone) [a  u1]",
+				"SELECT id FROM tb_person WHERE userid=? [u1]",
+			},
+			id:    1,
+			hooks: "PreInsert,BeforeInsert,PreUpdate,BeforeUpdate",
+		},
+		{
+			name:     "before update vetoes",
+			dialect:  Oracle,
+			p:        upsertPerson{Userid: "u1", veto: "update"},
+			conflict: []string{"userid"},
+			hooks:    "PreInsert,BeforeInsert,PreUpdate,BeforeUpdate",
+			err:      "invalid update",
+		},
+		{
+			name:     "unknown conflict column",
+			dialect:  Oracle,
+			p:        upsertPerson{Id: 3},
+			conflict: []string{"card"},
+			hooks:    "PreInsert,BeforeInsert,PreUpdate,BeforeUpdate",
+			err:      "Upsert conflict column not found: card",
+		},
+	}
+	for _, tt := range tests {
+
+		db, d := newFakeDB(tt.dialect)
+		q := db.Table("tb_person")
+		if tt.fields != "" {
+			q = q.Select(tt.fields)
+		}
+
+		p := tt.p
+		err := q.Upsert(&p, tt.conflict...)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: err = %v, want %s", tt.name, err, tt.err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		got := d.statements()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: statements = %q, want %q", tt.name, got, tt.want)
+		} else {
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("%s: statement %d = %q, want %q", tt.name, i, got[i], tt.want[i])
+				}
+			}
+		}
+		if p.Id != tt.id && tt.err == "" {
+			t.Errorf("%s: Id = %d, want %d", tt.name, p.Id, tt.id)
+		}
+		if hooks := strings.Join(p.hooks, ","); hooks != tt.hooks {
+			t.Errorf("%s: hooks = %s, want %s", tt.name, hooks, tt.hooks)
+		}
+	}
+}